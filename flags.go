@@ -0,0 +1,162 @@
+package main
+
+import (
+	"calderat/objects"
+	"flag"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// parseConfig parses the flags of a command into its configuration: the config
+// file and profile picked by -config and -profile, or the defaults, with the
+// flags given on the command line taking precedence over both.
+func parseConfig(command string, args []string, bind func(*flag.FlagSet, *objects.OperationConfig)) (*objects.OperationConfig, error) {
+	config := objects.DefaultOperationConfig()
+	flags := newFlagSet(command, "")
+	configFile := flags.String("config", "", "YAML or JSON config file of the operation, see calderat.example.yml")
+	profile := flags.String("profile", "", "Profile of the config file to use, e.g. lab or customer-safe")
+	bind(flags, config)
+	flags.Parse(args)
+	if *configFile == "" {
+		if *profile != "" {
+			return nil, fmt.Errorf("-profile %s needs a -config file", *profile)
+		}
+		return config, nil
+	}
+
+	config, err := objects.LoadOperationConfig(*configFile, *profile)
+	if err != nil {
+		return nil, err
+	}
+	// Parse again over the file, which only changes the flags that were given
+	overrides := flag.NewFlagSet(command, flag.ContinueOnError)
+	overrides.String("config", "", "")
+	overrides.String("profile", "", "")
+	bind(overrides, config)
+	if err := overrides.Parse(args); err != nil {
+		return nil, err
+	}
+	return config, nil
+}
+
+// bindPaths binds the flags locating the files of an engagement, so one binary
+// can serve several engagements from their own data and output directories.
+func bindPaths(flags *flag.FlagSet, config *objects.OperationConfig) {
+	flags.StringVar(&config.DataDir, "data-dir", config.DataDir, "Directory holding abilities/, payloads/, adversary.yml and source.yml")
+	flags.StringVar(&config.Adversary, "adversary", config.Adversary, "Adversary file (default <data-dir>/adversary.yml)")
+	flags.Var(&listFlag{list: &config.Sources}, "source", "Fact source file, repeatable or comma-separated (default <data-dir>/source.yml)")
+	bindOutput(flags, config)
+}
+
+// bindOutput only binds the output directory, for commands working on the files
+// of a past operation.
+func bindOutput(flags *flag.FlagSet, config *objects.OperationConfig) {
+	flags.StringVar(&config.OutputDir, "output-dir", config.OutputDir, "Directory the logs, facts, cleanup links and journal are written to")
+}
+
+// bindLogLevel binds the log level of the config.
+func bindLogLevel(flags *flag.FlagSet, config *objects.OperationConfig) {
+	flags.StringVar(&config.LogLevel, "log-level", config.LogLevel, "Set the log level (TRACE, DEBUG, INFO, WARN, ERROR)")
+}
+
+// bindRun binds the flags of the settings of an operation.
+func bindRun(flags *flag.FlagSet, config *objects.OperationConfig) {
+	bindPaths(flags, config)
+	bindLogLevel(flags, config)
+	flags.Var(&deferCleanupFlag{policy: &config.Cleanup}, "non-cleanup", "Save cleanup links to cleanups.json instead of running them")
+	flags.Var(&negatedFlag{value: &config.Autonomous}, "non-auto", "Enable non-auto mode")
+	flags.StringVar(&config.PayloadURL, "payload-url", config.PayloadURL, "Base URL to download payloads missing from <data-dir>/payloads/")
+	flags.StringVar(&config.WorkDir, "work-dir", config.WorkDir, "Directory payloads are staged in and links run from")
+	flags.StringVar(&config.ArtifactsDir, "artifacts-dir", config.ArtifactsDir, "Directory files listed in executor uploads are collected into (default <output-dir>/artifacts)")
+	flags.StringVar(&config.UploadURL, "upload-url", config.UploadURL, "Endpoint files listed in executor uploads are POSTed to instead of the artifacts directory")
+	flags.Var(&listFlag{list: &config.Executors}, "executors", "Comma-separated executor preference, e.g. psh,cmd or bash,sh")
+	flags.BoolVar(&config.ForcePrivileged, "force-privileged", config.ForcePrivileged, "Run abilities requiring elevation even when the agent is not elevated")
+	flags.Var(&negatedFlag{value: &config.Stream}, "no-stream", "Do not print link output while it runs")
+	flags.IntVar(&config.OutputLimit, "max-output", config.OutputLimit, "Bytes of stdout and stderr kept per link, the head and tail of longer output")
+	flags.StringVar(&config.Planner, "planner", config.Planner, "Set the planner (atomic, batch, buckets, look_ahead)")
+	flags.Var(&config.Jitter, "jitter", "Wait before each link, a range such as 1s-5s or a fixed duration (default 0s-4s)")
+	flags.Var(&config.Timeout, "timeout", "Timeout of links whose executor sets none, e.g. 90s")
+	flags.Var(&config.CleanupTimeout, "cleanup-timeout", "Timeout of cleanup links whose executor sets none")
+	flags.Var(&listFlag{list: &config.Outputs}, "outputs", "Comma-separated files to write: attire (log.json), facts (facts.json)")
+}
+
+// listFlag is a list flag that can be repeated and takes comma-separated values.
+// The first value given replaces the list of the config.
+type listFlag struct {
+	list *[]string
+	set  bool
+}
+
+func (l *listFlag) String() string {
+	if l.list == nil {
+		return ""
+	}
+	return strings.Join(*l.list, ",")
+}
+
+func (l *listFlag) Set(value string) error {
+	if !l.set {
+		*l.list, l.set = nil, true
+	}
+	for _, item := range strings.Split(value, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			*l.list = append(*l.list, item)
+		}
+	}
+	return nil
+}
+
+// negatedFlag is a boolean flag turning a setting off, such as -no-stream.
+type negatedFlag struct {
+	value *bool
+}
+
+func (n *negatedFlag) IsBoolFlag() bool {
+	return true
+}
+
+func (n *negatedFlag) String() string {
+	if n.value == nil {
+		return "false"
+	}
+	return strconv.FormatBool(!*n.value)
+}
+
+func (n *negatedFlag) Set(value string) error {
+	on, err := strconv.ParseBool(value)
+	if err != nil {
+		return err
+	}
+	*n.value = !on
+	return nil
+}
+
+// deferCleanupFlag is the -non-cleanup flag, which defers the cleanup links to
+// the cleanup command.
+type deferCleanupFlag struct {
+	policy *string
+}
+
+func (d *deferCleanupFlag) IsBoolFlag() bool {
+	return true
+}
+
+func (d *deferCleanupFlag) String() string {
+	if d.policy == nil {
+		return "false"
+	}
+	return strconv.FormatBool(*d.policy == objects.CLEANUP_DEFER)
+}
+
+func (d *deferCleanupFlag) Set(value string) error {
+	on, err := strconv.ParseBool(value)
+	if err != nil {
+		return err
+	}
+	*d.policy = objects.CLEANUP_RUN
+	if on {
+		*d.policy = objects.CLEANUP_DEFER
+	}
+	return nil
+}
@@ -0,0 +1,168 @@
+package main
+
+import (
+	"calderat/objects"
+	"calderat/secondclass"
+	"calderat/service/knowledge"
+	logger "calderat/utils/logger"
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"text/tabwriter"
+)
+
+// list prints the abilities of the adversary per platform, the adversaries or
+// the facts of the sources. Abilities are listed when no topic is given.
+func list(args []string) int {
+	topic := "abilities"
+	if len(args) > 0 && !strings.HasPrefix(args[0], "-") {
+		topic, args = args[0], args[1:]
+	}
+	logLevel, platform := "", ""
+	config, err := parseConfig("list "+topic, args, func(flags *flag.FlagSet, config *objects.OperationConfig) {
+		bindPaths(flags, config)
+		flags.StringVar(&logLevel, "log-level", "WARN", "Set the log level (TRACE, DEBUG, INFO, WARN, ERROR)")
+		if topic == "abilities" {
+			flags.StringVar(&platform, "platform", "", "Only show this platform (linux, windows, darwin or an alias)")
+		}
+	})
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 2
+	}
+
+	log, err := logger.New(logLevel)
+	if err != nil {
+		fmt.Printf("Failed to initialize logger: %v", err)
+		return 1
+	}
+
+	switch topic {
+	case "abilities":
+		return listPlatforms(config, platform, log)
+	case "adversaries":
+		return listAdversaries(config, log)
+	case "facts":
+		return listFacts(config, log)
+	}
+	fmt.Fprintf(os.Stderr, "Unknown list %q, expected abilities, adversaries or facts\n", topic)
+	return 2
+}
+
+// listPlatforms prints which abilities of the adversary can run on each platform,
+// with the executors that would run them.
+func listPlatforms(config *objects.OperationConfig, platform string, log *logger.Logger) int {
+	platforms := secondclass.Platforms
+	if platform != "" {
+		platforms = []string{secondclass.NormalizePlatform(platform)}
+	}
+
+	abilities, err := objects.LoadRunnableAbilities(config.AbilitiesDir(), log, knowledge.NewKnowledgeService(log))
+	if err != nil {
+		log.Log(logger.ERROR, "Failed to load abilities: %v", err)
+		return 1
+	}
+	byId := map[string]objects.Ability{}
+	for _, ability := range abilities {
+		byId[ability.AbilityId] = ability
+	}
+	adversary := objects.NewAdversaryWithLogger(log)
+	if err := adversary.LoadFromYAML(config.AdversaryFile()); err != nil {
+		return 1
+	}
+
+	writer := tabwriter.NewWriter(os.Stdout, 0, 4, 2, ' ', 0)
+	fmt.Fprintf(writer, "#\tABILITY\t%s\n", strings.ToUpper(strings.Join(platforms, "\t")))
+	counts := make([]int, len(platforms))
+	for index, abilityId := range adversary.AtomicOrdering {
+		ability, exists := byId[abilityId]
+		if !exists {
+			fmt.Fprintf(writer, "%d\t%s (unknown ability)\t%s\n", index+1, abilityId, strings.Repeat("-\t", len(platforms)))
+			continue
+		}
+		columns := []string{}
+		for i, platform := range platforms {
+			executors := ability.PlatformExecutors(platform)
+			if len(executors) == 0 {
+				columns = append(columns, "-")
+				continue
+			}
+			counts[i]++
+			columns = append(columns, strings.Join(executors, ","))
+		}
+		fmt.Fprintf(writer, "%d\t%s\t%s\n", index+1, ability.Name, strings.Join(columns, "\t"))
+	}
+	totals := []string{}
+	for _, count := range counts {
+		totals = append(totals, fmt.Sprintf("%d/%d", count, len(adversary.AtomicOrdering)))
+	}
+	fmt.Fprintf(writer, "\tTOTAL\t%s\n", strings.Join(totals, "\t"))
+	writer.Flush()
+	return 0
+}
+
+// listAdversaries prints the adversary file and the adversaries kept in
+// <data-dir>/adversaries/, any of which can be passed to -adversary.
+func listAdversaries(config *objects.OperationConfig, log *logger.Logger) int {
+	files := []string{config.AdversaryFile()}
+	extra, _ := filepath.Glob(filepath.Join(config.DataDir, "adversaries", "*.yml"))
+	for _, file := range extra {
+		if filepath.Clean(file) != filepath.Clean(files[0]) {
+			files = append(files, file)
+		}
+	}
+
+	status := 0
+	writer := tabwriter.NewWriter(os.Stdout, 0, 4, 2, ' ', 0)
+	fmt.Fprintf(writer, "FILE\tID\tNAME\tABILITIES\n")
+	for _, file := range files {
+		adversary := objects.NewAdversaryWithLogger(log)
+		if err := adversary.LoadFromYAML(file); err != nil {
+			status = 1
+			continue
+		}
+		fmt.Fprintf(writer, "%s\t%s\t%s\t%d\n", file, adversary.AdversaryId, adversary.Name, len(adversary.AtomicOrdering))
+	}
+	writer.Flush()
+	return status
+}
+
+// listFacts prints the facts and relationships the sources seed the operation with.
+func listFacts(config *objects.OperationConfig, log *logger.Logger) int {
+	status := 0
+	writer := tabwriter.NewWriter(os.Stdout, 0, 4, 2, ' ', 0)
+	fmt.Fprintf(writer, "SOURCE\tTRAIT\tVALUE\n")
+	for _, file := range config.SourceFiles() {
+		source := objects.Source{Logger: log}
+		if err := source.LoadFromYAML(file); err != nil {
+			status = 1
+			continue
+		}
+		for _, fact := range source.Facts {
+			fmt.Fprintf(writer, "%s\t%s\t%s\n", file, fact.Trait, fact.Value)
+		}
+		for _, relationship := range source.Relationships {
+			traits, values := describeRelationship(relationship)
+			fmt.Fprintf(writer, "%s\t%s\t%s\n", file, traits, values)
+		}
+	}
+	writer.Flush()
+	return status
+}
+
+// describeRelationship returns the traits joined by the edge, e.g.
+// host.user.name -has_password-> host.user.password, and the values.
+func describeRelationship(relationship secondclass.Relationship) (string, string) {
+	traits, values := "", ""
+	if relationship.Source != nil {
+		traits, values = relationship.Source.Trait, relationship.Source.Value
+	}
+	traits += " -" + relationship.Edge + "->"
+	if relationship.Target != nil {
+		traits += " " + relationship.Target.Trait
+		values += " -> " + relationship.Target.Value
+	}
+	return traits, values
+}
@@ -3,65 +3,225 @@ package main
 import (
 	"calderat/objects"
 	"calderat/secondclass"
-	"calderat/service/knowledge"
-	"calderat/utils/data"
 	"calderat/utils/envdetector"
 	logger "calderat/utils/logger"
 	"flag"
 	"fmt"
+	"os"
+	"os/signal"
 	"strings"
+	"syscall"
 )
 
+const usage = `Usage: calderat <command> [flags]
+
+Commands:
+  run                                Run the adversary (the default command)
+  resume                             Resume the operation journaled in the output directory
+  cleanup                            Run the cleanup links saved in the output directory
+  validate                           Lint the abilities, adversary and fact sources, exit 1 on problems
+  list abilities|adversaries|facts   List the abilities of the adversary per platform, the adversaries or the facts
+  report                             Summarize the ATTiRe log of the output directory
+
+Run "calderat <command> -h" for the flags of a command.
+`
+
 func main() {
+	args := os.Args[1:]
+	command := "run"
+	if len(args) > 0 && !strings.HasPrefix(args[0], "-") {
+		command, args = args[0], args[1:]
+	}
+	switch command {
+	case "run":
+		os.Exit(runOperation(args, false))
+	case "resume":
+		os.Exit(runOperation(args, true))
+	case "cleanup":
+		os.Exit(runCleanup(args))
+	case "validate":
+		os.Exit(validate(args))
+	case "list":
+		os.Exit(list(args))
+	case "report":
+		os.Exit(report(args))
+	case "help", "-h", "-help":
+		fmt.Print(usage)
+	default:
+		fmt.Fprintf(os.Stderr, "Unknown command %q\n\n%s", command, usage)
+		os.Exit(2)
+	}
+}
 
-	logLevelFlag := flag.String("log-level", "INFO", "Set the log level (TRACE, DEBUG, INFO, WARN, ERROR)")
-	nonCleanupMode := flag.Bool("non-cleanup", false, "Disable cleanup operation")
-	nonAutonomousMode := flag.Bool("non-auto", false, "Enable non-auto mode")
-	cleanupOp := flag.Bool("cleanup-op", false, "Cleanup current operation")
-	flag.Parse()
+// newFlagSet returns the flag set of a command, which exits on bad flags.
+func newFlagSet(command, arguments string) *flag.FlagSet {
+	flags := flag.NewFlagSet(command, flag.ExitOnError)
+	flags.Usage = func() {
+		fmt.Fprintf(flags.Output(), "Usage: calderat %s [flags]%s\n", command, arguments)
+		flags.PrintDefaults()
+	}
+	return flags
+}
+
+// runOperation runs the adversary, or resumes the operation journaled in the
+// output directory.
+func runOperation(args []string, resume bool) int {
+	command := "run"
+	if resume {
+		command = "resume"
+	}
+	dryRun, planFile := false, ""
+	config, err := parseConfig(command, args, func(flags *flag.FlagSet, config *objects.OperationConfig) {
+		bindRun(flags, config)
+		if !resume {
+			flags.BoolVar(&dryRun, "dry-run", false, "Print the links the operation would run, in order, without executing them or writing results")
+			flags.StringVar(&planFile, "plan-file", "", "Export the links of a dry run to a JSON file")
+		}
+	})
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 2
+	}
 
 	// Initialize a centralized logger with a specified log level
-	log, err := logger.New(*logLevelFlag)
+	log, err := logger.New(config.LogLevel)
 	if err != nil {
 		fmt.Printf("Failed to initialize logger: %v", err)
-		return
+		return 1
 	}
 
-	env, err := envdetector.DetectEnvironment(log)
+	env, ipaddrs, err := detectEnvironment(log)
 	if err != nil {
 		log.Log(logger.ERROR, "Failed to detect environment: %v", err)
-		return
+		return 1
 	}
-	ipaddrs, err := env.GetAllIPAddresses()
-	log.Log(logger.INFO, "Agent information:\n[+] Operating System: %s\n[+] Shells: %s\nAvailable IP Addresses: %s",
-		env.OS, strings.Join(env.ShortnameShells, ", "), strings.Join(ipaddrs, ", "))
-
-	if *cleanupOp {
-		cleanupLinks, err := secondclass.LoadCleanupLinksFromJson("cleanups.json", log)
-		if err != nil {
-			log.Log(logger.ERROR, "Failed to load cleanup links: %v", err)
-			return
+
+	agent := objects.Agent{OS: env.OS, Shells: env.ShortnameShells, IP: ipaddrs[0], Privilege: env.Privilege}
+	operation, err := objects.NewOperationFromConfig(config, agent, log)
+	if err != nil {
+		log.Log(logger.ERROR, "Failed to create operation: %v", err)
+		return 1
+	}
+
+	if dryRun {
+		plan := operation.Plan()
+		operation.PrintPlan(plan)
+		if planFile != "" {
+			if err := objects.WritePlan(plan, planFile); err != nil {
+				log.Log(logger.ERROR, "Failed to write plan: %v", err)
+				return 1
+			}
 		}
-		operation := objects.NewCleanupOperation(cleanupLinks, env.ShortnameShells, env.OS, ipaddrs[0], log)
-		operation.RunningCleanupOperation()
-		return
+		return 0
 	}
 
-	// ----------------------------------------------------------------
-	knowledgeService := knowledge.NewKnowledgeService(log)
-	abilities, err := data.ProcessYmlAbilities("data/abilities/", log, knowledgeService)
+	if err := os.MkdirAll(config.OutputDir, 0755); err != nil {
+		log.Log(logger.ERROR, "Failed to create output directory: %v", err)
+		return 1
+	}
 
+	journal, err := openJournal(operation, config.OutputPath("journal.jsonl"), resume, log)
 	if err != nil {
-		log.Log(logger.ERROR, "Failed to load abilities: %v", err)
-		return
+		log.Log(logger.ERROR, "%v", err)
+		return 1
 	}
+	if journal == nil {
+		return 0
+	}
+	defer journal.Close()
+	operation.Journal = journal
 
-	adversary := objects.Adversary{}
-	adversary.Logger = log
-	adversary.LoadFromYAML("data/adversary.yml")
+	stopOnSignal(operation, log)
+	operation.Run()
+	return 0
+}
 
-	operation := objects.NewOperation(adversary, !*nonAutonomousMode, !*nonCleanupMode, abilities, env.ShortnameShells, env.OS, ipaddrs[0], log, knowledgeService)
+// runCleanup runs the cleanup links an operation saved in the output directory
+// because it ran without cleanup or was quit during cleanup.
+func runCleanup(args []string) int {
+	config, err := parseConfig("cleanup", args, func(flags *flag.FlagSet, config *objects.OperationConfig) {
+		bindOutput(flags, config)
+		bindLogLevel(flags, config)
+	})
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 2
+	}
 
-	operation.Run()
+	log, err := logger.New(config.LogLevel)
+	if err != nil {
+		fmt.Printf("Failed to initialize logger: %v", err)
+		return 1
+	}
+	env, ipaddrs, err := detectEnvironment(log)
+	if err != nil {
+		log.Log(logger.ERROR, "Failed to detect environment: %v", err)
+		return 1
+	}
+
+	cleanupLinks, err := secondclass.LoadCleanupLinksFromJson(config.OutputPath("cleanups.json"), log)
+	if err != nil {
+		log.Log(logger.ERROR, "Failed to load cleanup links: %v", err)
+		return 1
+	}
+	agent := objects.Agent{OS: env.OS, Shells: env.ShortnameShells, IP: ipaddrs[0], Privilege: env.Privilege}
+	operation := objects.NewCleanupOperation(config, cleanupLinks, agent, log)
+	stopOnSignal(operation, log)
+	operation.RunningCleanupOperation()
+	return 0
+}
+
+// detectEnvironment detects the agent and logs what it found.
+func detectEnvironment(log *logger.Logger) (*envdetector.Environment, []string, error) {
+	env, err := envdetector.DetectEnvironment(log)
+	if err != nil {
+		return nil, nil, err
+	}
+	ipaddrs, err := env.GetAllIPAddresses()
+	if err != nil {
+		return nil, nil, err
+	}
+	if len(ipaddrs) == 0 {
+		return nil, nil, fmt.Errorf("no IPv4 address found")
+	}
+	log.Log(logger.INFO, "Agent information:\n[+] Operating System: %s\n[+] Shells: %s\n[+] Elevated: %t (%s)\nAvailable IP Addresses: %s",
+		env.OS, strings.Join(env.ShortnameShells, ", "), env.Privilege.Elevated, env.Privilege.Detail, strings.Join(ipaddrs, ", "))
+	return env, ipaddrs, nil
+}
+
+// openJournal creates the journal of a new operation, or restores the operation
+// from its journal and reopens it when resuming. It returns nil without an error
+// when the journaled operation already finished.
+func openJournal(operation *objects.Operation, path string, resume bool, log *logger.Logger) (*objects.Journal, error) {
+	if !resume {
+		return objects.NewJournal(path)
+	}
+	state, err := objects.ReadJournal(path)
+	if err != nil {
+		return nil, fmt.Errorf("cannot resume: %w", err)
+	}
+	if state.Adversary != operation.Adversary.Name {
+		return nil, fmt.Errorf("cannot resume: journal records adversary %q, not %q", state.Adversary, operation.Adversary.Name)
+	}
+	if state.Finished {
+		log.Log(logger.WARN, "Operation %s already finished, nothing to resume", state.OperationId)
+		return nil, nil
+	}
+	operation.Resume(state)
+	return objects.OpenJournal(path)
+}
 
+// stopOnSignal stops the operation on the first SIGINT or SIGTERM, which kills the
+// running link and lets the queued cleanup links run. A second signal exits at once.
+func stopOnSignal(operation *objects.Operation, log *logger.Logger) {
+	signals := make(chan os.Signal, 2)
+	signal.Notify(signals, os.Interrupt, syscall.SIGTERM)
+	go func() {
+		sig := <-signals
+		log.Log(logger.WARN, "Received %s, stopping the operation and running queued cleanup (send again to exit now)", sig)
+		operation.Stop()
+		sig = <-signals
+		log.Log(logger.ERROR, "Received %s again, exiting without finishing cleanup, remaining cleanup links saved to %s", sig, operation.SavePendingCleanup())
+		os.Exit(1)
+	}()
 }
@@ -3,10 +3,12 @@ package objects
 import (
 	"calderat/secondclass"
 	"calderat/service/knowledge"
+	"calderat/service/requirement"
 	"calderat/utils/logger"
 	"errors"
 	"fmt"
 	"os"
+	"path/filepath"
 	"slices"
 	"strings"
 	"time"
@@ -17,19 +19,26 @@ import (
 
 const (
 	DefaultTactic = "null_tactic"
+	// ElevatedPrivilege marks abilities that need root or an elevated token.
+	ElevatedPrivilege = "Elevated"
 )
 
 // Ability represents a configurable ability loaded from a YAML file.
 type Ability struct {
-	AbilityId        string                 `yaml:"id"`
-	Tactic           string                 `yaml:"tactic"`
-	Technique        string                 `yaml:"technique_name"`
-	TechniqueId      string                 `yaml:"technique_id"`
-	Name             string                 `yaml:"name"`
-	Description      string                 `yaml:"description"`
-	Executors        []secondclass.Executor `yaml:"executors"`
-	Privilege        string                 `yaml:"privilege"`
-	DeletePayload    bool                   `yaml:"delete_payload"`
+	AbilityId     string                    `yaml:"id"`
+	Tactic        string                    `yaml:"tactic"`
+	Technique     string                    `yaml:"technique_name"`
+	TechniqueId   string                    `yaml:"technique_id"`
+	Name          string                    `yaml:"name"`
+	Description   string                    `yaml:"description"`
+	Executors     []secondclass.Executor    `yaml:"executors"`
+	Requirements  []secondclass.Requirement `yaml:"requirements"`
+	Buckets       []string                  `yaml:"buckets"`
+	Privilege     string                    `yaml:"privilege"`
+	DeletePayload bool                      `yaml:"delete_payload"`
+	Repeatable    bool                      `yaml:"repeatable"`
+	// File is the file the ability was loaded from, if any
+	File             string `yaml:"-"`
 	KnowledgeService *knowledge.KnowledgeService
 	Logger           *logger.Logger
 }
@@ -115,37 +124,147 @@ func (a *Ability) LoadFromYAML(filePath string) error {
 	return nil
 }
 
-func (a *Ability) IsAvailable(shells []string) bool {
+// RequiresElevation reports whether the ability is marked as needing elevated privileges.
+func (a *Ability) RequiresElevation() bool {
+	return strings.EqualFold(strings.TrimSpace(a.Privilege), ElevatedPrivilege)
+}
+
+func (a *Ability) IsAvailable(platform string, shells []string) bool {
+	return len(a.EligibleExecutors(platform, shells, nil)) > 0
+}
+
+// EligibleExecutors returns the executors targeting the platform whose shell is
+// available, ordered by the preference list first and by their order in the
+// ability after that.
+func (a *Ability) EligibleExecutors(platform string, shells []string, preference []string) []secondclass.Executor {
+	eligible := []secondclass.Executor{}
+	for _, name := range preference {
+		for _, executor := range a.Executors {
+			if executor.Name == name && executor.RunsOn(platform) && slices.Contains(shells, executor.Name) {
+				eligible = append(eligible, executor)
+			}
+		}
+	}
 	for _, executor := range a.Executors {
-		if slices.Contains(shells, executor.Name) {
-			return true
+		if !slices.Contains(preference, executor.Name) && executor.RunsOn(platform) && slices.Contains(shells, executor.Name) {
+			eligible = append(eligible, executor)
 		}
 	}
-	return false
+	return eligible
+}
+
+// PlatformExecutors returns the names of the executors targeting the platform,
+// regardless of the shells available on this agent.
+func (a *Ability) PlatformExecutors(platform string) []string {
+	names := []string{}
+	for _, executor := range a.Executors {
+		if executor.RunsOn(platform) && !slices.Contains(names, executor.Name) {
+			names = append(names, executor.Name)
+		}
+	}
+	return names
+}
+
+// CreateLinks builds the links and cleanup links of the preferred available executor.
+func (a *Ability) CreateLinks(log *logger.Logger, platform string, shells []string, preference []string) ([]secondclass.Link, []secondclass.Link) {
+	executors := a.EligibleExecutors(platform, shells, preference)
+	if len(executors) == 0 {
+		return []secondclass.Link{}, []secondclass.Link{}
+	}
+	return a.CreateExecutorLinks(log, executors[0])
 }
 
-func (a *Ability) CreateLinks(log *logger.Logger, shells []string, facts map[string][]*secondclass.Fact) ([]secondclass.Link, []secondclass.Link) {
+// CreateExecutorLinks builds the links and cleanup links of an executor, one per
+// fact combination that satisfies the ability requirements.
+func (a *Ability) CreateExecutorLinks(log *logger.Logger, executor secondclass.Executor) ([]secondclass.Link, []secondclass.Link) {
 	links := []secondclass.Link{}
 	cleanupLinks := []secondclass.Link{}
-	for _, executor := range a.Executors {
-		if slices.Contains(shells, executor.Name) {
-			commands := a.KnowledgeService.ReplaceFacts(executor.Command, facts)
-			for _, command := range commands {
-				links = append(links, *secondclass.NewLink(a.Name, a.AbilityId, a.TechniqueId, command, executor, time.Duration(executor.Timeout)*time.Second, log, false))
-			}
-			for i := len(executor.Cleanup) - 1; i >= 0; i-- {
-				commands = a.KnowledgeService.ReplaceFacts(executor.Cleanup[i], facts)
-				for _, command := range commands {
-					cleanupLinks = append(cleanupLinks, *secondclass.NewLink(a.Name, a.AbilityId, a.TechniqueId, command, executor, time.Duration(executor.Timeout)*time.Second, log, true))
-				}
+	combinations := a.KnowledgeService.ReplaceFacts(executor.Body())
+	for _, combination := range combinations {
+		if !requirement.Enforce(a.Requirements, combination.Used, a.KnowledgeService, log) {
+			log.Log(logger.DEBUG, "Skipping command %s of ability %s: requirements not satisfied", combination.Command, a.Name)
+			continue
+		}
+		for _, stdin := range a.stdinCombinations(executor, combination) {
+			link := secondclass.NewLink(a.Name, a.AbilityId, a.TechniqueId, combination.Command, executor, time.Duration(executor.Timeout)*time.Second, log, false)
+			link.Stdin = stdin.Command
+			link.Used = stdin.Used
+			links = append(links, *link)
+		}
+	}
+	for i := len(executor.Cleanup) - 1; i >= 0; i-- {
+		combinations = a.KnowledgeService.ReplaceFacts(executor.Cleanup[i])
+		for _, combination := range combinations {
+			if !requirement.Enforce(a.Requirements, combination.Used, a.KnowledgeService, log) {
+				continue
 			}
-			break
+			link := secondclass.NewLink(a.Name, a.AbilityId, a.TechniqueId, combination.Command, executor, executor.CleanupTimeout(), log, true)
+			link.Used = combination.Used
+			cleanupLinks = append(cleanupLinks, *link)
 		}
 	}
 	return links, cleanupLinks
 }
 
+// stdinCombinations fills the stdin of an executor with the facts of a command
+// combination, expanding the facts only the stdin uses. Each returned combination
+// holds the stdin and every fact used by the command and the stdin.
+func (a *Ability) stdinCombinations(executor secondclass.Executor, command knowledge.Combination) []knowledge.Combination {
+	if executor.Stdin == "" {
+		return []knowledge.Combination{command}
+	}
+	return a.KnowledgeService.ReplaceFactsWithUsed(executor.Stdin, command.Used)
+}
+
+// FallbackLink builds a link that runs the same fact combination as the given
+// link with the next eligible executor after the ones it already tried.
+// It returns nil when no executor is left.
+func (a *Ability) FallbackLink(log *logger.Logger, link *secondclass.Link, platform string, shells []string, preference []string) (*secondclass.Link, []secondclass.Link) {
+	for _, executor := range a.EligibleExecutors(platform, shells, preference) {
+		if slices.Contains(link.ExecutorsTried, executor.Name) {
+			continue
+		}
+		var fallback *secondclass.Link
+		for _, combination := range a.KnowledgeService.ReplaceFactsWithUsed(executor.Body(), link.Used) {
+			if !requirement.Enforce(a.Requirements, combination.Used, a.KnowledgeService, log) {
+				continue
+			}
+			if stdins := a.stdinCombinations(executor, combination); len(stdins) > 0 {
+				fallback = secondclass.NewLink(a.Name, a.AbilityId, a.TechniqueId, combination.Command, executor, time.Duration(executor.Timeout)*time.Second, log, false)
+				fallback.Stdin = stdins[0].Command
+				fallback.Used = stdins[0].Used
+				break
+			}
+		}
+		if fallback == nil {
+			continue
+		}
+		fallback.ExecutorsTried = slices.Clone(link.ExecutorsTried)
+		return fallback, a.usedCleanupLinks(log, executor, fallback.Used)
+	}
+	return nil, nil
+}
+
+// usedCleanupLinks builds the cleanup links of an executor for the facts a link
+// used, so that cleanup only touches what the link did.
+func (a *Ability) usedCleanupLinks(log *logger.Logger, executor secondclass.Executor, used []*secondclass.Fact) []secondclass.Link {
+	cleanupLinks := []secondclass.Link{}
+	for i := len(executor.Cleanup) - 1; i >= 0; i-- {
+		for _, combination := range a.KnowledgeService.ReplaceFactsWithUsed(executor.Cleanup[i], used) {
+			if !requirement.Enforce(a.Requirements, combination.Used, a.KnowledgeService, log) {
+				continue
+			}
+			link := secondclass.NewLink(a.Name, a.AbilityId, a.TechniqueId, combination.Command, executor, executor.CleanupTimeout(), log, true)
+			link.Used = combination.Used
+			cleanupLinks = append(cleanupLinks, *link)
+		}
+	}
+	return cleanupLinks
+}
+
 // LoadMultipleFromYAML loads multiple abilities from the specified YAML file.
+// Every ability goes through the prehook like one loaded with LoadFromYAML; the
+// abilities that pass are returned with the errors of the others.
 func LoadMultipleAbilityFromYAML(filePath string, log *logger.Logger, knowledgeService *knowledge.KnowledgeService) ([]Ability, error) {
 	log.Log(logger.TRACE, "Loading YAML file: %s", filePath)
 
@@ -155,25 +274,99 @@ func LoadMultipleAbilityFromYAML(filePath string, log *logger.Logger, knowledgeS
 		return nil, fmt.Errorf("error reading file '%s': %w", filePath, err)
 	}
 
-	// data, err = prehook(data)
-	// if err != nil {
-	// 	log.Log(logger.ERROR, "Error in prehook for file '%s': %v", filePath, err)
-	// 	return nil, fmt.Errorf("error in prehook for file '%s': %w", filePath, err)
-	// }
-
-	var abilities []Ability
-	err = yaml.Unmarshal(data, &abilities)
+	var entries []map[string]interface{}
+	err = yaml.Unmarshal(data, &entries)
 	if err != nil {
 		log.Log(logger.ERROR, "Failed to unmarshal YAML for file '%s': %v", filePath, err)
 		return nil, fmt.Errorf("error unmarshalling YAML for file '%s': %w", filePath, err)
 	}
 
-	// Set the logger for each ability
-	for i := range abilities {
-		abilities[i].Logger = log
-		abilities[i].KnowledgeService = knowledgeService
+	abilities := []Ability{}
+	errs := []error{}
+	for i, entry := range entries {
+		processedData, err := prehook(entry)
+		if err != nil {
+			log.Log(logger.ERROR, "Error in prehook for ability %d of file '%s': %v", i+1, filePath, err)
+			errs = append(errs, fmt.Errorf("error in prehook for ability %d of file '%s': %w", i+1, filePath, err))
+			continue
+		}
+		ability := Ability{Logger: log, KnowledgeService: knowledgeService, File: filePath}
+		if err := yaml.Unmarshal(processedData, &ability); err != nil {
+			log.Log(logger.ERROR, "Failed to unmarshal ability %d of file '%s': %v", i+1, filePath, err)
+			errs = append(errs, fmt.Errorf("error unmarshalling ability %d of file '%s': %w", i+1, filePath, err))
+			continue
+		}
+		abilities = append(abilities, ability)
 	}
 
 	log.Log(logger.TRACE, "Successfully loaded %d abilities from file: %s", len(abilities), filePath)
+	return abilities, errors.Join(errs...)
+}
+
+// LoadAbilitiesFromDir loads the abilities of every .yml file under a folder.
+// A file or ability that fails to load, or an ability whose id is already
+// defined, does not stop the others: the abilities loaded are returned with the
+// errors of the rest.
+func LoadAbilitiesFromDir(folder string, log *logger.Logger, knowledgeService *knowledge.KnowledgeService) ([]Ability, error) {
+	abilities := []Ability{}
+	files := map[string]string{} // File of each ability id
+	errs := []error{}
+	err := filepath.Walk(folder, func(path string, info os.FileInfo, err error) error {
+		if err != nil {
+			return fmt.Errorf("error accessing path %s: %w", path, err)
+		}
+		if info.IsDir() || filepath.Ext(path) != ".yml" {
+			return nil
+		}
+		log.Log(logger.TRACE, "Processing file: %s", path)
+		loaded, err := LoadMultipleAbilityFromYAML(path, log, knowledgeService)
+		if err != nil {
+			errs = append(errs, err)
+		}
+		for _, ability := range loaded {
+			if other, exists := files[ability.AbilityId]; exists {
+				errs = append(errs, fmt.Errorf("%s: ability %s is also defined in %s", path, ability.AbilityId, other))
+				continue
+			}
+			files[ability.AbilityId] = path
+			abilities = append(abilities, ability)
+		}
+		return nil
+	})
+	if err != nil {
+		errs = append(errs, fmt.Errorf("error walking the path %s: %w", folder, err))
+	}
+	return abilities, errors.Join(errs...)
+}
+
+// LoadErrors splits the error returned by LoadAbilitiesFromDir into one error
+// per file or ability that failed to load.
+func LoadErrors(err error) []error {
+	if err == nil {
+		return nil
+	}
+	joined, ok := err.(interface{ Unwrap() []error })
+	if !ok {
+		return []error{err}
+	}
+	errs := []error{}
+	for _, e := range joined.Unwrap() {
+		errs = append(errs, LoadErrors(e)...)
+	}
+	return errs
+}
+
+// LoadRunnableAbilities loads the abilities of a folder for a run: abilities
+// that fail to load are logged and skipped, the validate command reports them.
+// It fails only when no ability loads.
+func LoadRunnableAbilities(folder string, log *logger.Logger, knowledgeService *knowledge.KnowledgeService) ([]Ability, error) {
+	abilities, err := LoadAbilitiesFromDir(folder, log, knowledgeService)
+	if err == nil {
+		return abilities, nil
+	}
+	if len(abilities) == 0 {
+		return nil, err
+	}
+	log.Log(logger.WARN, "Skipping %d abilities or files that failed to load, run validate for details", len(LoadErrors(err)))
 	return abilities, nil
 }
@@ -0,0 +1,78 @@
+package objects
+
+import (
+	"bufio"
+	"calderat/secondclass"
+	"calderat/utils/colorprint"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"time"
+)
+
+// Approver decides whether a pending link runs when the operation is not autonomous.
+// It may edit the command of the link before approving it.
+type Approver interface {
+	Approve(link *secondclass.Link) secondclass.Approval
+}
+
+// ConsoleApprover asks the operator on a terminal.
+type ConsoleApprover struct {
+	in  *bufio.Reader
+	out io.Writer
+}
+
+func NewConsoleApprover(in io.Reader, out io.Writer) *ConsoleApprover {
+	return &ConsoleApprover{in: bufio.NewReader(in), out: out}
+}
+
+// NewTerminalApprover returns a ConsoleApprover reading stdin and writing stdout.
+func NewTerminalApprover() *ConsoleApprover {
+	return NewConsoleApprover(os.Stdin, os.Stdout)
+}
+
+// Approve shows the link and prompts until the operator approves, skips, edits or quits.
+// A closed input stops the run.
+func (ca *ConsoleApprover) Approve(link *secondclass.Link) secondclass.Approval {
+	fmt.Fprintln(ca.out, colorprint.ColorString(fmt.Sprintf("    [?] Ability:   %s", link.ProcedureName), colorprint.CYAN))
+	fmt.Fprintln(ca.out, colorprint.ColorString(fmt.Sprintf("        Technique: %s", link.MitreTechniqueId), colorprint.CYAN))
+	fmt.Fprintln(ca.out, colorprint.ColorString(fmt.Sprintf("        Executor:  %s", link.Executor.Name), colorprint.CYAN))
+	fmt.Fprintln(ca.out, colorprint.ColorString(fmt.Sprintf("        Command:   %s", link.Command), colorprint.CYAN))
+	for {
+		fmt.Fprint(ca.out, "    [a]pprove, [s]kip, [e]dit, [q]uit > ")
+		answer, err := ca.readLine()
+		if err != nil {
+			return secondclass.Approval{Decision: secondclass.QUIT, Time: time.Now().UTC()}
+		}
+		switch strings.ToLower(answer) {
+		case "a", "approve", "y", "yes":
+			return secondclass.Approval{Decision: secondclass.APPROVED, Time: time.Now().UTC()}
+		case "s", "skip", "n", "no":
+			return secondclass.Approval{Decision: secondclass.SKIPPED, Time: time.Now().UTC()}
+		case "q", "quit":
+			return secondclass.Approval{Decision: secondclass.QUIT, Time: time.Now().UTC()}
+		case "e", "edit":
+			fmt.Fprint(ca.out, "    New command > ")
+			command, err := ca.readLine()
+			if err != nil {
+				return secondclass.Approval{Decision: secondclass.QUIT, Time: time.Now().UTC()}
+			}
+			if command == "" || command == link.Command {
+				fmt.Fprintln(ca.out, "    Command unchanged")
+				continue
+			}
+			approval := secondclass.Approval{Decision: secondclass.EDITED, OriginalCommand: link.Command, Time: time.Now().UTC()}
+			link.Command = command
+			return approval
+		}
+	}
+}
+
+func (ca *ConsoleApprover) readLine() (string, error) {
+	line, err := ca.in.ReadString('\n')
+	if err != nil && line == "" {
+		return "", err
+	}
+	return strings.TrimSpace(line), nil
+}
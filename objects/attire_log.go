@@ -2,6 +2,7 @@ package objects
 
 import (
 	"calderat/secondclass"
+	"calderat/service/execute"
 	"calderat/utils/random"
 	"encoding/json"
 	"os"
@@ -17,17 +18,37 @@ type Procedure struct {
 	Order                int         `json:"order"`
 	Steps                []Step      `json:"steps"`
 	CleanupCommands      []Step      `json:"cleanupCommands"`
+	// Privilege records why an ability requiring elevation ran or was skipped
+	Privilege *PrivilegeDecision `json:"privilege,omitempty"`
+}
+
+const (
+	PRIVILEGE_RUN     = "run"
+	PRIVILEGE_FORCED  = "forced"
+	PRIVILEGE_SKIPPED = "skipped"
+)
+
+// PrivilegeDecision is the outcome of checking an ability privilege against the agent.
+type PrivilegeDecision struct {
+	Required string `json:"required"`
+	Elevated bool   `json:"elevated"`
+	Decision string `json:"decision"`
+	Reason   string `json:"reason"`
 }
 
 func NewProcedure(link *secondclass.Link, order int) *Procedure {
+	return newProcedure(link.ProcedureName, link.ProcedureId, link.MitreTechniqueId, order)
+}
+
+func newProcedure(name, id, techniqueId string, order int) *Procedure {
 	return &Procedure{
-		ProcedureName:        link.ProcedureName,
-		ProcedureDescription: link.ProcedureName,
+		ProcedureName:        name,
+		ProcedureDescription: name,
 		ProcedureId: ProcedureId{
 			Type: "guid",
-			Id:   link.ProcedureId,
+			Id:   id,
 		},
-		MitreTechniqueId: link.MitreTechniqueId,
+		MitreTechniqueId: techniqueId,
 		Order:            order,
 		Steps:            []Step{},
 		CleanupCommands:  []Step{},
@@ -53,27 +74,43 @@ type ProcedureId struct {
 }
 
 type Step struct {
-	Command   string        `json:"command"`
-	Executor  string        `json:"executor"`
-	Order     int           `json:"order"`
-	Output    []OutputBlock `json:"output"`
-	TimeStart string        `json:"time-start"`
-	TimeStop  string        `json:"time-stop"`
+	Command   string               `json:"command"`
+	Executor  string               `json:"executor"`
+	Order     int                  `json:"order"`
+	Output    []OutputBlock        `json:"output"`
+	TimeStart string               `json:"time-start"`
+	TimeStop  string               `json:"time-stop"`
+	Uploads   []secondclass.Upload `json:"uploads,omitempty"`
+	// ExecutorsTried lists every executor attempted, ending with the one that ran
+	ExecutorsTried []string `json:"executors-tried,omitempty"`
+	// Approval is the operator decision in manual mode
+	Approval *secondclass.Approval `json:"approval,omitempty"`
+	ExitCode int                   `json:"exit-code"`
+	// Background is set when the command ran alongside other steps until collected
+	Background bool `json:"background,omitempty"`
+	// Error explains a failure that is not in the command output, e.g. a timeout
+	Error string `json:"error,omitempty"`
 }
 
 func NewStep(link *secondclass.Link, order int) *Step {
 	output := []OutputBlock{}
-	output = append(output, *NewOutputBlock(link.Out, "STDOUT"))
-	if link.Err != "" {
-		output = append(output, *NewOutputBlock(link.Err, "STDERR"))
+	output = append(output, *newCapturedBlock(link.Out, "STDOUT", link.OutCapture))
+	if link.Err != "" || link.ErrCapture.File != "" {
+		output = append(output, *newCapturedBlock(link.Err, "STDERR", link.ErrCapture))
 	}
 	return &Step{
-		Command:   link.Command,
-		Executor:  link.Executor.Name,
-		Order:     order,
-		Output:    output,
-		TimeStart: link.DecidedTime.UTC().Format("2006-01-02T15:04:05.000Z"),
-		TimeStop:  link.FinishedTime.UTC().Format("2006-01-02T15:04:05.000Z"),
+		Command:        link.Command,
+		Executor:       link.Executor.Name,
+		Order:          order,
+		Output:         output,
+		TimeStart:      link.DecidedTime.UTC().Format("2006-01-02T15:04:05.000Z"),
+		TimeStop:       link.FinishedTime.UTC().Format("2006-01-02T15:04:05.000Z"),
+		Uploads:        link.Uploads,
+		ExecutorsTried: link.ExecutorsTried,
+		Approval:       link.Approval,
+		ExitCode:       link.ExitCode,
+		Background:     link.Executor.Background,
+		Error:          link.Error,
 	}
 }
 
@@ -81,6 +118,8 @@ type OutputBlock struct {
 	Type    string `json:"type"`
 	Content string `json:"content"`
 	Level   string `json:"level"`
+	// Capture tells when the content was truncated, decoded or is binary
+	Capture *execute.Capture `json:"capture,omitempty"`
 }
 
 func NewOutputBlock(content, level string) *OutputBlock {
@@ -91,10 +130,21 @@ func NewOutputBlock(content, level string) *OutputBlock {
 	}
 }
 
+// newCapturedBlock returns an output block with the capture of the output when
+// it is more than plain UTF-8 text kept in full.
+func newCapturedBlock(content, level string, capture execute.Capture) *OutputBlock {
+	block := NewOutputBlock(content, level)
+	if capture.Truncated || capture.Binary || (capture.Encoding != "" && capture.Encoding != execute.UTF8) {
+		block.Capture = &capture
+	}
+	return block
+}
+
 type AttireLog struct {
 	AttireVersion string                 `json:"attire-version"`
 	ExecutionData map[string]interface{} `json:"execution-data"`
 	Procedures    []*Procedure           `json:"procedures"`
+	Ignored       []IgnoredAbility       `json:"ignored-abilities,omitempty"`
 }
 
 func NewAttireLog(ip string) *AttireLog {
@@ -172,3 +222,14 @@ func (al *AttireLog) AddLinkResult(link *secondclass.Link) {
 		curr_procedure.AddStep(link, len(curr_procedure.Steps)+1)
 	}
 }
+
+// RecordPrivilege attaches a privilege decision to the procedure of an ability,
+// creating the procedure when the ability has not run yet.
+func (al *AttireLog) RecordPrivilege(ability *Ability, decision PrivilegeDecision) {
+	procedure := al.GetProcedureByName(ability.Name)
+	if procedure == nil {
+		procedure = newProcedure(ability.Name, ability.AbilityId, ability.TechniqueId, len(al.Procedures)+1)
+		al.AddProcedure(procedure)
+	}
+	procedure.Privilege = &decision
+}
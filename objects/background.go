@@ -0,0 +1,73 @@
+package objects
+
+import (
+	"calderat/secondclass"
+	"calderat/service/execute"
+	"calderat/utils/colorprint"
+	"fmt"
+	"strings"
+	"time"
+)
+
+// backgroundLink is a link whose command keeps running while the operation goes on.
+type backgroundLink struct {
+	ability Ability
+	link    *secondclass.Link
+	handle  *execute.Handle
+}
+
+// startBackground starts a link in the background and reports whether it is
+// running. A link that could not start is finished like any other link.
+func (o *Operation) startBackground(ability Ability, link *secondclass.Link) bool {
+	o.applyDefaults(link)
+	handle := link.Start(o.ctx, o.executingService(link))
+	if handle == nil {
+		return false
+	}
+	until := "the end of the operation"
+	if link.Executor.StopAfter != "" {
+		until = o.abilityLabel(link.Executor.StopAfter, o.Abilities[link.Executor.StopAfter].Name)
+	}
+	fmt.Println(colorprint.ColorString(fmt.Sprintf("    [~] %s running in the background (pid %d) until %s", ability.Name, handle.Pid, until), colorprint.CYAN))
+	o.background = append(o.background, backgroundLink{ability: ability, link: link, handle: handle})
+	return true
+}
+
+// collectBackground stops the background links that stop after the ability,
+// or every background link when the ability id is empty, and records them with
+// the output they collected.
+func (o *Operation) collectBackground(abilityId string) {
+	running := []backgroundLink{}
+	for _, background := range o.background {
+		if abilityId != "" && background.link.Executor.StopAfter != abilityId {
+			running = append(running, background)
+			continue
+		}
+		link := background.link
+		link.Collect(background.handle)
+		o.spillBinaryOutput(link, &link.Out, &link.OutCapture, "stdout.bin")
+		o.spillBinaryOutput(link, &link.Err, &link.ErrCapture, "stderr.bin")
+		fmt.Println(colorprint.ColorString(fmt.Sprintf("\n[~] Collected background %s after %s", background.ability.Name, link.Duration().Round(time.Millisecond)), colorprint.CYAN))
+		if o.StreamOutput {
+			printOutput(link.Out, link.OutCapture, false)
+			printOutput(link.Err, link.ErrCapture, true)
+		}
+		o.finishLink(background.ability, link)
+		o.recordLink(link)
+	}
+	o.background = running
+}
+
+// printOutput prints collected text output line by line, like streamed output.
+func printOutput(output string, capture execute.Capture, stderr bool) {
+	if output == "" || capture.Binary {
+		return
+	}
+	for _, line := range strings.Split(strings.TrimRight(output, "\n"), "\n") {
+		if stderr {
+			fmt.Println(colorprint.ColorString("    ! "+line, colorprint.RED))
+		} else {
+			fmt.Println("    | " + line)
+		}
+	}
+}
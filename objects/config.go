@@ -0,0 +1,308 @@
+package objects
+
+import (
+	"calderat/service/execute"
+	"calderat/utils/random"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"gopkg.in/yaml.v2"
+)
+
+// Cleanup policies: run the cleanup links at the end of the operation, or save
+// them to cleanups.json for the cleanup command.
+const (
+	CLEANUP_RUN   = "run"
+	CLEANUP_DEFER = "defer"
+)
+
+// Output files an operation can write besides its cleanup links and journal.
+const (
+	OUTPUT_ATTIRE = "attire" // ATTiRe log, log.json
+	OUTPUT_FACTS  = "facts"  // Knowledge learned, facts.json
+)
+
+// OperationConfig is everything an operation is built from, read from a YAML or
+// JSON file whose named profiles override its top-level settings.
+type OperationConfig struct {
+	LogLevel  string   `yaml:"log_level" json:"log_level"`
+	DataDir   string   `yaml:"data_dir" json:"data_dir"`
+	Adversary string   `yaml:"adversary" json:"adversary"` // Default <data_dir>/adversary.yml
+	Sources   []string `yaml:"sources" json:"sources"`     // Default <data_dir>/source.yml
+	OutputDir string   `yaml:"output_dir" json:"output_dir"`
+
+	Planner         string   `yaml:"planner" json:"planner"`
+	Autonomous      bool     `yaml:"autonomous" json:"autonomous"`
+	Cleanup         string   `yaml:"cleanup" json:"cleanup"`
+	Stream          bool     `yaml:"stream" json:"stream"`
+	OutputLimit     int      `yaml:"output_limit" json:"output_limit"`
+	Outputs         []string `yaml:"outputs" json:"outputs"`
+	Executors       []string `yaml:"executors" json:"executors"`
+	ForcePrivileged bool     `yaml:"force_privileged" json:"force_privileged"`
+	// Jitter is waited before each link, the default of 0s-4s when empty
+	Jitter JitterRange `yaml:"jitter" json:"jitter"`
+	// Timeout and CleanupTimeout apply to links whose executor sets no timeout
+	Timeout        Duration `yaml:"timeout" json:"timeout"`
+	CleanupTimeout Duration `yaml:"cleanup_timeout" json:"cleanup_timeout"`
+	// Allow, when not empty, restricts the abilities run; Deny skips abilities
+	Allow AbilityFilter `yaml:"allow" json:"allow"`
+	Deny  AbilityFilter `yaml:"deny" json:"deny"`
+
+	PayloadURL   string `yaml:"payload_url" json:"payload_url"`
+	WorkDir      string `yaml:"work_dir" json:"work_dir"`
+	ArtifactsDir string `yaml:"artifacts_dir" json:"artifacts_dir"` // Default <output_dir>/artifacts
+	UploadURL    string `yaml:"upload_url" json:"upload_url"`
+}
+
+// configFile is the layout of a config file: the settings and their profiles.
+type configFile struct {
+	OperationConfig `yaml:",inline"`
+	Profiles        map[string]interface{} `yaml:"profiles" json:"profiles"`
+}
+
+// DefaultOperationConfig returns the settings used when no config file is given.
+func DefaultOperationConfig() *OperationConfig {
+	return &OperationConfig{
+		LogLevel:    "INFO",
+		DataDir:     "data",
+		OutputDir:   ".",
+		Planner:     "atomic",
+		Autonomous:  true,
+		Cleanup:     CLEANUP_RUN,
+		Stream:      true,
+		OutputLimit: execute.DefaultOutputLimit,
+		Outputs:     []string{OUTPUT_ATTIRE, OUTPUT_FACTS},
+		WorkDir:     ".",
+	}
+}
+
+// LoadOperationConfig reads a config file over the defaults, then the profile
+// when one is named. Files ending in .json are JSON, anything else is YAML.
+func LoadOperationConfig(path, profile string) (*OperationConfig, error) {
+	rawData, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("error reading config '%s': %w", path, err)
+	}
+	unmarshal, marshal := yaml.Unmarshal, yaml.Marshal
+	if strings.EqualFold(filepath.Ext(path), ".json") {
+		unmarshal, marshal = json.Unmarshal, json.Marshal
+	}
+
+	file := configFile{OperationConfig: *DefaultOperationConfig()}
+	if err := unmarshal(rawData, &file); err != nil {
+		return nil, fmt.Errorf("error unmarshalling config '%s': %w", path, err)
+	}
+	config := file.OperationConfig
+	if profile == "" {
+		return &config, nil
+	}
+	settings, exists := file.Profiles[profile]
+	if !exists {
+		return nil, fmt.Errorf("config '%s' has no profile %q (profiles: %s)", path, profile, strings.Join(file.profileNames(), ", "))
+	}
+	// The profile is decoded over the settings, replacing only what it sets
+	rawProfile, err := marshal(settings)
+	if err == nil {
+		err = unmarshal(rawProfile, &config)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("error unmarshalling profile %q of config '%s': %w", profile, path, err)
+	}
+	return &config, nil
+}
+
+func (f *configFile) profileNames() []string {
+	names := []string{}
+	for name := range f.Profiles {
+		names = append(names, name)
+	}
+	return names
+}
+
+// Validate checks the settings of the config. The planner is checked by
+// NewPlanner when the operation is built.
+func (c *OperationConfig) Validate() error {
+	if c.Cleanup != CLEANUP_RUN && c.Cleanup != CLEANUP_DEFER {
+		return fmt.Errorf("unknown cleanup policy %q, expected %s or %s", c.Cleanup, CLEANUP_RUN, CLEANUP_DEFER)
+	}
+	for _, output := range c.Outputs {
+		if output != OUTPUT_ATTIRE && output != OUTPUT_FACTS {
+			return fmt.Errorf("unknown output %q, expected %s or %s", output, OUTPUT_ATTIRE, OUTPUT_FACTS)
+		}
+	}
+	if c.Jitter.Min < 0 || c.Jitter.Max < c.Jitter.Min {
+		return fmt.Errorf("invalid jitter range %s", c.Jitter)
+	}
+	return nil
+}
+
+func (c *OperationConfig) AbilitiesDir() string {
+	return filepath.Join(c.DataDir, "abilities")
+}
+
+func (c *OperationConfig) PayloadsDir() string {
+	return filepath.Join(c.DataDir, "payloads")
+}
+
+func (c *OperationConfig) AdversaryFile() string {
+	if c.Adversary != "" {
+		return c.Adversary
+	}
+	return filepath.Join(c.DataDir, "adversary.yml")
+}
+
+func (c *OperationConfig) SourceFiles() []string {
+	if len(c.Sources) > 0 {
+		return c.Sources
+	}
+	return []string{filepath.Join(c.DataDir, "source.yml")}
+}
+
+func (c *OperationConfig) ArtifactsPath() string {
+	if c.ArtifactsDir != "" {
+		return c.ArtifactsDir
+	}
+	return c.OutputPath("artifacts")
+}
+
+// OutputPath returns the path of a file in the output directory.
+func (c *OperationConfig) OutputPath(name string) string {
+	return filepath.Join(c.OutputDir, name)
+}
+
+// AbilityFilter selects abilities by id, tactic or technique. A technique also
+// selects its sub-techniques, e.g. T1059 selects T1059.001.
+type AbilityFilter struct {
+	Abilities  []string `yaml:"abilities" json:"abilities"`
+	Tactics    []string `yaml:"tactics" json:"tactics"`
+	Techniques []string `yaml:"techniques" json:"techniques"`
+}
+
+func (f AbilityFilter) Empty() bool {
+	return len(f.Abilities) == 0 && len(f.Tactics) == 0 && len(f.Techniques) == 0
+}
+
+// Match returns what selects the ability, or an empty string when nothing does.
+func (f AbilityFilter) Match(ability Ability) string {
+	for _, id := range f.Abilities {
+		if id == ability.AbilityId {
+			return "ability " + id
+		}
+	}
+	for _, tactic := range f.Tactics {
+		if strings.EqualFold(tactic, ability.Tactic) {
+			return "tactic " + tactic
+		}
+	}
+	for _, technique := range f.Techniques {
+		if strings.EqualFold(technique, ability.TechniqueId) || strings.HasPrefix(strings.ToUpper(ability.TechniqueId), strings.ToUpper(technique)+".") {
+			return "technique " + technique
+		}
+	}
+	return ""
+}
+
+// Duration is a time.Duration read from a string such as 90s or 2m, or from a
+// number of seconds like executor timeouts.
+type Duration time.Duration
+
+func (d Duration) String() string {
+	return time.Duration(d).String()
+}
+
+func (d *Duration) Set(value string) error {
+	if seconds, err := strconv.ParseFloat(value, 64); err == nil {
+		*d = Duration(seconds * float64(time.Second))
+		return nil
+	}
+	duration, err := time.ParseDuration(value)
+	if err != nil {
+		return fmt.Errorf("invalid duration %q", value)
+	}
+	*d = Duration(duration)
+	return nil
+}
+
+func (d *Duration) UnmarshalYAML(unmarshal func(interface{}) error) error {
+	var value string
+	if err := unmarshal(&value); err != nil {
+		return err
+	}
+	return d.Set(value)
+}
+
+func (d *Duration) UnmarshalJSON(data []byte) error {
+	return d.Set(strings.Trim(string(data), `"`))
+}
+
+func (d Duration) MarshalJSON() ([]byte, error) {
+	return json.Marshal(d.String())
+}
+
+// JitterRange is the range the wait before a link is picked from, written as
+// 1s-5s, or a single duration for a fixed wait.
+type JitterRange struct {
+	Min     time.Duration
+	Max     time.Duration
+	defined bool
+}
+
+// Defined reports whether the range was set, as 0s disables the jitter.
+func (r JitterRange) Defined() bool {
+	return r.defined
+}
+
+func (r JitterRange) String() string {
+	if !r.defined {
+		return ""
+	}
+	if r.Min == r.Max {
+		return r.Min.String()
+	}
+	return r.Min.String() + "-" + r.Max.String()
+}
+
+func (r *JitterRange) Set(value string) error {
+	bounds := strings.SplitN(value, "-", 2)
+	var min, max Duration
+	if err := min.Set(strings.TrimSpace(bounds[0])); err != nil {
+		return fmt.Errorf("invalid jitter %q", value)
+	}
+	max = min
+	if len(bounds) == 2 {
+		if err := max.Set(strings.TrimSpace(bounds[1])); err != nil {
+			return fmt.Errorf("invalid jitter %q", value)
+		}
+	}
+	r.Min, r.Max, r.defined = time.Duration(min), time.Duration(max), true
+	return nil
+}
+
+// Pick returns a random wait within the range.
+func (r JitterRange) Pick() time.Duration {
+	if r.Max <= r.Min {
+		return r.Min
+	}
+	return r.Min + time.Duration(random.SecureRandomInt(int64(r.Max-r.Min)+1))
+}
+
+func (r *JitterRange) UnmarshalYAML(unmarshal func(interface{}) error) error {
+	var value string
+	if err := unmarshal(&value); err != nil {
+		return err
+	}
+	return r.Set(value)
+}
+
+func (r *JitterRange) UnmarshalJSON(data []byte) error {
+	return r.Set(strings.Trim(string(data), `"`))
+}
+
+func (r JitterRange) MarshalJSON() ([]byte, error) {
+	return json.Marshal(r.String())
+}
@@ -0,0 +1,121 @@
+package objects
+
+import (
+	"calderat/service/knowledge"
+	"calderat/utils/logger"
+	"fmt"
+	"strings"
+)
+
+// Reasons an ability of the adversary did not run.
+const (
+	IGNORED_UNKNOWN_ID        = "unknown-id"
+	IGNORED_PLATFORM          = "platform"
+	IGNORED_NO_EXECUTOR       = "no-executor"
+	IGNORED_PRIVILEGE         = "privilege"
+	IGNORED_UNMET_FACT        = "unmet-fact"
+	IGNORED_UNMET_REQUIREMENT = "unmet-requirement"
+	IGNORED_DENIED            = "denied"
+)
+
+// IgnoredAbility records an ability of the atomic ordering that produced no link.
+type IgnoredAbility struct {
+	AbilityId string `json:"ability-id"`
+	Name      string `json:"name,omitempty"`
+	Reason    string `json:"reason"`
+	Detail    string `json:"detail"`
+}
+
+// ignore records why an ability was skipped, once per ability. Abilities that
+// already ran a link are never recorded.
+func (o *Operation) ignore(abilityId, name, reason, detail string) {
+	for _, ignored := range o.Ignored {
+		if ignored.AbilityId == abilityId {
+			return
+		}
+	}
+	if o.hasLinks(abilityId) {
+		return
+	}
+	ignored := IgnoredAbility{AbilityId: abilityId, Name: name, Reason: reason, Detail: detail}
+	o.Ignored = append(o.Ignored, ignored)
+	o.attireLog.Ignored = append(o.attireLog.Ignored, ignored)
+	o.Logger.Log(logger.WARN, "Ignoring ability %s (%s): %s", o.abilityLabel(abilityId, name), reason, detail)
+}
+
+// isAvailable checks whether an ability of the atomic ordering can run on this
+// agent and records the reason when it cannot.
+func (o *Operation) isAvailable(abilityId string) (Ability, bool) {
+	ability, exists := o.Abilities[abilityId]
+	if !exists {
+		o.ignore(abilityId, "", IGNORED_UNKNOWN_ID, "no ability with this id in the catalog")
+		return ability, false
+	}
+	if !o.Allow.Empty() && o.Allow.Match(ability) == "" {
+		o.ignore(abilityId, ability.Name, IGNORED_DENIED, "not in the allow list")
+		return ability, false
+	}
+	if match := o.Deny.Match(ability); match != "" {
+		o.ignore(abilityId, ability.Name, IGNORED_DENIED, "deny list matches "+match)
+		return ability, false
+	}
+	if !ability.IsAvailable(o.os, o.usableShells()) {
+		executors := ability.PlatformExecutors(o.os)
+		if len(executors) == 0 {
+			o.ignore(abilityId, ability.Name, IGNORED_PLATFORM, fmt.Sprintf("no executor targets %s", o.os))
+		} else {
+			o.ignore(abilityId, ability.Name, IGNORED_NO_EXECUTOR, fmt.Sprintf("needs one of %s, agent has %s",
+				strings.Join(executors, ","), strings.Join(o.usableShells(), ",")))
+		}
+		return ability, false
+	}
+	return ability, o.privilegeAllows(ability)
+}
+
+// ignoreUnlinkedAbilities records the available abilities that never produced a
+// link, either because a fact was never known or because a requirement failed.
+func (o *Operation) ignoreUnlinkedAbilities() {
+	_, abilities := o.AvailableAbilities()
+	for _, ability := range abilities {
+		if o.hasLinks(ability.AbilityId) {
+			continue
+		}
+		executors := ability.EligibleExecutors(o.os, o.usableShells(), o.ExecutorPreference)
+		if len(executors) == 0 {
+			continue
+		}
+		missing := []string{}
+		for _, trait := range o.KnowledgeService.RequiredTraits(executors[0].Body() + "\n" + executors[0].Stdin) {
+			if len(o.KnowledgeService.GetFacts(knowledge.FactCriteria{Trait: trait})) == 0 {
+				missing = append(missing, "#{"+trait+"}")
+			}
+		}
+		if len(missing) > 0 {
+			o.ignore(ability.AbilityId, ability.Name, IGNORED_UNMET_FACT, "no fact for "+strings.Join(missing, ", "))
+		} else {
+			o.ignore(ability.AbilityId, ability.Name, IGNORED_UNMET_REQUIREMENT, "no fact combination satisfies the requirements")
+		}
+	}
+}
+
+// hasLinks reports whether an ability ran at least one link, or planned one in a dry run.
+func (o *Operation) hasLinks(abilityId string) bool {
+	for _, link := range o.Links {
+		if link.ProcedureId == abilityId {
+			return true
+		}
+	}
+	for _, planned := range o.plan {
+		if planned.AbilityId == abilityId {
+			return true
+		}
+	}
+	return false
+}
+
+func (o *Operation) abilityLabel(abilityId, name string) string {
+	if name == "" {
+		return abilityId
+	}
+	return fmt.Sprintf("%s(%s)", name, abilityId)
+}
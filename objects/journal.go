@@ -0,0 +1,231 @@
+package objects
+
+import (
+	"bufio"
+	"bytes"
+	"calderat/secondclass"
+	"calderat/utils/logger"
+	"encoding/json"
+	"fmt"
+	"os"
+	"sync"
+	"time"
+)
+
+// Journal entry types, in the order an operation writes them.
+const (
+	JOURNAL_START        = "start"        // The operation started
+	JOURNAL_RESUME       = "resume"       // The operation was resumed from the journal
+	JOURNAL_ABILITY      = "ability"      // The planner reached an ability of the atomic ordering
+	JOURNAL_LINK         = "link"         // A link finished
+	JOURNAL_FACT         = "fact"         // A fact was learned
+	JOURNAL_RELATIONSHIP = "relationship" // A relationship was learned
+	JOURNAL_CLEANUP      = "cleanup"      // A cleanup link was queued
+	JOURNAL_CLEANED      = "cleaned"      // A queued cleanup link ran
+	JOURNAL_FINISH       = "finish"       // The operation finished, there is nothing to resume
+)
+
+// JournalEntry is a line of the journal. Only the fields of its type are set.
+type JournalEntry struct {
+	Type         string                    `json:"type"`
+	Time         time.Time                 `json:"time"`
+	OperationId  string                    `json:"operation-id,omitempty"`
+	Adversary    string                    `json:"adversary,omitempty"`
+	Index        int                       `json:"index,omitempty"`
+	AbilityId    string                    `json:"ability-id,omitempty"`
+	Link         *secondclass.Link         `json:"link,omitempty"`
+	Fact         *secondclass.Fact         `json:"fact,omitempty"`
+	Relationship *secondclass.Relationship `json:"relationship,omitempty"`
+}
+
+// Journal is a write-ahead log of the state of an operation: every entry is
+// synced to disk before the operation goes on, so a crashed or rebooted run can
+// be resumed from its last finished link.
+type Journal struct {
+	file  *os.File
+	mutex sync.Mutex
+}
+
+// NewJournal creates an empty journal file for a new operation.
+func NewJournal(path string) (*Journal, error) {
+	return openJournal(path, os.O_TRUNC)
+}
+
+// OpenJournal opens the journal file of an operation to resume for appending.
+// A last line cut short by a crash is dropped so new entries start on a line.
+func OpenJournal(path string) (*Journal, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open journal: %w", err)
+	}
+	if end := bytes.LastIndexByte(data, '\n') + 1; end < len(data) {
+		if err := os.Truncate(path, int64(end)); err != nil {
+			return nil, fmt.Errorf("failed to repair journal: %w", err)
+		}
+	}
+	return openJournal(path, os.O_APPEND)
+}
+
+func openJournal(path string, mode int) (*Journal, error) {
+	file, err := os.OpenFile(path, os.O_CREATE|os.O_WRONLY|mode, 0600)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open journal: %w", err)
+	}
+	return &Journal{file: file}, nil
+}
+
+// Write appends an entry to the journal and syncs it to disk.
+func (j *Journal) Write(entry JournalEntry) error {
+	j.mutex.Lock()
+	defer j.mutex.Unlock()
+	if entry.Time.IsZero() {
+		entry.Time = time.Now().UTC()
+	}
+	data, err := json.Marshal(entry)
+	if err != nil {
+		return err
+	}
+	if _, err := j.file.Write(append(data, '\n')); err != nil {
+		return err
+	}
+	return j.file.Sync()
+}
+
+func (j *Journal) Close() error {
+	return j.file.Close()
+}
+
+// JournalState is the state of an operation replayed from its journal.
+type JournalState struct {
+	OperationId   string
+	Adversary     string
+	Position      int    // Index in the atomic ordering of the last ability reached
+	AbilityId     string // Last ability reached
+	Links         []secondclass.Link
+	Facts         []*secondclass.Fact
+	Relationships []*secondclass.Relationship
+	CleanupLinks  []secondclass.Link // Queued cleanup links that did not run yet
+	Cleaned       []secondclass.Link // Cleanup links that ran
+	Finished      bool
+}
+
+// ReadJournal replays a journal. A last line cut short by a crash is ignored.
+func ReadJournal(path string) (*JournalState, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open journal: %w", err)
+	}
+	defer file.Close()
+
+	state := &JournalState{}
+	scanner := bufio.NewScanner(file)
+	scanner.Buffer(make([]byte, 0, 64*1024), 64*1024*1024)
+	var broken error
+	for line := 1; scanner.Scan(); line++ {
+		if broken != nil {
+			return nil, broken
+		}
+		var entry JournalEntry
+		if err := json.Unmarshal(scanner.Bytes(), &entry); err != nil {
+			broken = fmt.Errorf("journal line %d is corrupt: %w", line, err)
+			continue
+		}
+		state.apply(entry)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read journal: %w", err)
+	}
+	if state.OperationId == "" {
+		return nil, fmt.Errorf("journal %s does not start an operation", path)
+	}
+	return state, nil
+}
+
+func (s *JournalState) apply(entry JournalEntry) {
+	switch entry.Type {
+	case JOURNAL_START:
+		*s = JournalState{OperationId: entry.OperationId, Adversary: entry.Adversary}
+	case JOURNAL_RESUME:
+		s.Finished = false
+	case JOURNAL_ABILITY:
+		s.Position, s.AbilityId = entry.Index, entry.AbilityId
+	case JOURNAL_LINK:
+		if entry.Link != nil {
+			s.Links = append(s.Links, *entry.Link)
+		}
+	case JOURNAL_FACT:
+		if entry.Fact != nil {
+			s.Facts = append(s.Facts, entry.Fact)
+		}
+	case JOURNAL_RELATIONSHIP:
+		if entry.Relationship != nil {
+			s.Relationships = append(s.Relationships, entry.Relationship)
+		}
+	case JOURNAL_CLEANUP:
+		if entry.Link != nil {
+			s.CleanupLinks = append(s.CleanupLinks, *entry.Link)
+		}
+	case JOURNAL_CLEANED:
+		if entry.Link == nil {
+			return
+		}
+		s.Cleaned = append(s.Cleaned, *entry.Link)
+		for i, link := range s.CleanupLinks {
+			if link.LinkId == entry.Link.LinkId {
+				s.CleanupLinks = append(s.CleanupLinks[:i], s.CleanupLinks[i+1:]...)
+				break
+			}
+		}
+	case JOURNAL_FINISH:
+		s.Finished = true
+	}
+}
+
+// journal writes an entry to the journal of the operation, if it keeps one.
+func (o *Operation) journal(entry JournalEntry) {
+	if o.Journal == nil {
+		return
+	}
+	if err := o.Journal.Write(entry); err != nil {
+		o.Logger.Log(logger.ERROR, "Failed to write journal: %v", err)
+	}
+}
+
+// Resume restores the state of an operation from its journal: finished links
+// are not run again, learned facts are known again and queued cleanup links
+// that did not run are run in the cleanup phase. Links interrupted by a stop
+// did not finish and run again.
+func (o *Operation) Resume(state *JournalState) {
+	o.OperationID = state.OperationId
+	o.resuming = true
+	for _, fact := range state.Facts {
+		o.KnowledgeService.AddFact(fact)
+	}
+	for _, relationship := range state.Relationships {
+		o.KnowledgeService.AddRelationship(relationship)
+	}
+	for i := range state.Links {
+		link := &state.Links[i]
+		if link.Status == secondclass.INTERRUPTED {
+			continue
+		}
+		link.Logger = o.Logger
+		o.finished[linkSignature(link)]++
+		o.executed[linkSignature(link)] = true
+		o.Links = append(o.Links, *link)
+		o.KnowledgeService.AddUsage(link.LinkId, link.Used)
+		o.attireLog.AddLinkResult(link)
+	}
+	for _, link := range state.CleanupLinks {
+		link.Logger = o.Logger
+		o.executed[linkSignature(&link)] = true
+		o.CleanupLinks = append(o.CleanupLinks, link)
+	}
+	// Cleanup links that ran are queued again when a link that needs them runs
+	// again, like a link interrupted before the cleanup phase
+	for i := range state.Cleaned {
+		o.attireLog.AddLinkResult(&state.Cleaned[i])
+	}
+	o.Logger.Log(logger.INFO, "Resuming operation %s after ability %d (%s): %d links finished, %d facts learned, %d cleanup links queued",
+		o.OperationID, state.Position, state.AbilityId, len(state.Links), len(state.Facts), len(state.CleanupLinks))
+}
@@ -0,0 +1,109 @@
+package objects
+
+import (
+	"calderat/secondclass"
+	"calderat/service/execute"
+	"calderat/service/knowledge"
+	"fmt"
+	"regexp"
+	"slices"
+	"strings"
+	"time"
+)
+
+var (
+	// techniqueIdPattern matches MITRE ATT&CK technique ids such as T1059 or T1059.001.
+	techniqueIdPattern = regexp.MustCompile(`^T\d{4}(\.\d{3})?$`)
+	// placeholderPattern matches the #{trait} placeholders of commands.
+	placeholderPattern = regexp.MustCompile(knowledge.FACTRGX)
+)
+
+// LintAbility returns the problems of an ability that only show up when it runs:
+// executors no agent has, placeholders no fact fills, invalid technique ids and
+// timeouts of zero. Known holds the traits the sources and parsers provide, and
+// a default timeout covers the executors without one.
+func LintAbility(ability Ability, known map[string]bool, defaultTimeout time.Duration) []string {
+	problems := []string{}
+	if ability.TechniqueId == "" {
+		problems = append(problems, "no technique_id")
+	} else if !techniqueIdPattern.MatchString(ability.TechniqueId) {
+		problems = append(problems, fmt.Sprintf("technique_id %q is not an ATT&CK id such as T1059 or T1059.001", ability.TechniqueId))
+	}
+	if len(ability.Executors) == 0 {
+		problems = append(problems, "no executors")
+	}
+	for i, executor := range ability.Executors {
+		label := fmt.Sprintf("executor %d (%s)", i+1, executor.Name)
+		if executor.IsCode() {
+			if !execute.KnownLanguage(executor.CodeLanguage()) {
+				problems = append(problems, fmt.Sprintf("%s: unknown code language %q", label, executor.CodeLanguage()))
+			}
+		} else if !slices.Contains(ExecutorNames, executor.Name) {
+			problems = append(problems, fmt.Sprintf("%s: unknown executor, expected one of %s", label, strings.Join(ExecutorNames, ", ")))
+		}
+		if executor.Body() == "" {
+			problems = append(problems, fmt.Sprintf("%s: no command or code", label))
+		}
+		for _, platform := range strings.Split(executor.Platform, ",") {
+			if platform = secondclass.NormalizePlatform(platform); platform != "" && !slices.Contains(secondclass.Platforms, platform) {
+				problems = append(problems, fmt.Sprintf("%s: unknown platform %q", label, platform))
+			}
+		}
+		if executor.Timeout <= 0 && !executor.Background && defaultTimeout <= 0 {
+			problems = append(problems, fmt.Sprintf("%s: timeout %d times out at once, set a timeout or a default timeout", label, executor.Timeout))
+		}
+		templates := append([]string{executor.Body(), executor.Stdin}, executor.Cleanup...)
+		if missing := unknownTraits(templates, known); len(missing) > 0 {
+			problems = append(problems, fmt.Sprintf("%s: no source or parser provides a fact for %s", label, strings.Join(missing, ", ")))
+		}
+	}
+	return problems
+}
+
+// unknownTraits returns the placeholders of templates whose trait is not known.
+func unknownTraits(templates []string, known map[string]bool) []string {
+	missing := []string{}
+	for _, template := range templates {
+		for _, match := range placeholderPattern.FindAllStringSubmatch(template, -1) {
+			placeholder := "#{" + match[1] + "}"
+			if !known[match[1]] && !slices.Contains(missing, placeholder) {
+				missing = append(missing, placeholder)
+			}
+		}
+	}
+	return missing
+}
+
+// KnownTraits returns the traits the sources seed an operation with and the
+// traits the parsers of the abilities can learn.
+func KnownTraits(sources []Source, abilities []Ability) map[string]bool {
+	known := map[string]bool{}
+	for _, source := range sources {
+		for _, fact := range source.Facts {
+			known[fact.Trait] = true
+		}
+		for _, relationship := range source.Relationships {
+			if relationship.Source != nil {
+				known[relationship.Source.Trait] = true
+			}
+			if relationship.Target != nil {
+				known[relationship.Target.Trait] = true
+			}
+		}
+	}
+	for _, ability := range abilities {
+		for _, executor := range ability.Executors {
+			for _, parser := range executor.Parsers {
+				for _, config := range parser.ParserConfigs {
+					if config.Source != "" {
+						known[config.Source] = true
+					}
+					if config.Target != "" {
+						known[config.Target] = true
+					}
+				}
+			}
+		}
+	}
+	return known
+}
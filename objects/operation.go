@@ -3,10 +3,17 @@ package objects
 import (
 	"calderat/secondclass"
 	"calderat/service/execute"
+	"calderat/service/file"
 	"calderat/service/knowledge"
+	"calderat/service/parser"
 	"calderat/utils/colorprint"
+	"calderat/utils/envdetector"
 	"calderat/utils/logger"
+	"context"
 	"fmt"
+	"path/filepath"
+	"sync"
+	"time"
 
 	"github.com/google/uuid"
 	"golang.org/x/exp/slices"
@@ -19,25 +26,71 @@ const (
 )
 
 type Operation struct {
-	OperationID       string
-	Name              string
-	Adversary         Adversary
-	Abilities         map[string]Ability
-	Source            Source
-	Facts             map[string][]*secondclass.Fact
-	Autonomous        bool
-	Cleanup           bool
-	Links             []secondclass.Link
-	CleanupLinks      []secondclass.Link
-	Logger            *logger.Logger
-	Ignored           []Ability
-	Status            int
+	OperationID  string
+	Name         string
+	Adversary    Adversary
+	Abilities    map[string]Ability
+	Source       Source
+	Autonomous   bool
+	Cleanup      bool
+	Links        []secondclass.Link
+	CleanupLinks []secondclass.Link
+	Logger       *logger.Logger
+	Ignored      []IgnoredAbility
+	Status       int
+	Planner      Planner
+	// Approver is asked about every link when the operation is not autonomous
+	Approver Approver
+	// StreamOutput prints the output of links while they run
+	StreamOutput bool
+	// Events, when set, receives the output of running links line by line and
+	// must be drained by the receiver, otherwise links block
+	Events chan<- LinkOutput
+	// OutputLimit is the number of bytes of each output stream kept for links whose
+	// executor sets none, 0 for execute.DefaultOutputLimit
+	OutputLimit int
+	// ExecutorPreference orders the executors tried for an ability, e.g. psh,cmd
+	ExecutorPreference []string
+	// Privilege is what the agent runs with; ForcePrivileged runs elevated abilities without it
+	Privilege          envdetector.Privilege
+	ForcePrivileged    bool
+	privilegeDecisions map[string]PrivilegeDecision
+	executed           map[string]bool
+	unavailable        map[string]bool
+	background         []backgroundLink
+	// OutputDir is where the ATTiRe log, facts and cleanup links are written
+	OutputDir string
+	// Outputs lists the files written besides cleanup links, all of them when nil
+	Outputs []string
+	// Jitter, when defined, replaces the wait before each link
+	Jitter JitterRange
+	// DefaultTimeout and DefaultCleanupTimeout apply to links without a timeout
+	DefaultTimeout        time.Duration
+	DefaultCleanupTimeout time.Duration
+	// Allow, when not empty, restricts the abilities run; Deny skips abilities
+	Allow AbilityFilter
+	Deny  AbilityFilter
+	// Journal, when set, records the progress of the operation so it can be resumed
+	Journal  *Journal
+	resuming bool
+	// finished counts the links of a resumed operation that ran before, by signature
+	finished map[string]int
+	// cleaned counts the cleanup links at the end of CleanupLinks the cleanup phase ran
+	cleaned int
+	// dryRun plans links instead of running them, see Plan
+	dryRun            bool
+	plan              []PlannedLink
 	shells            []string
 	ExecutingServices map[string]execute.ExecutingService
 	KnowledgeService  *knowledge.KnowledgeService
+	FileService       *file.FileService
 	os                string
 	attireLog         AttireLog
 	ip                string
+	// ctx is cancelled by Stop to interrupt the running link
+	ctx    context.Context
+	cancel context.CancelFunc
+	mutex  sync.Mutex
 }
 
 func (o *Operation) AddAbility(ability Ability) {
@@ -57,35 +110,348 @@ func (o *Operation) AddAbilities(abilities []Ability) {
 }
 
 func (o *Operation) Run() {
-	o.Status = RUNNING
-	o.Logger.Log(logger.TRACE, "Running operation %s", o.Name)
+	o.setStatus(RUNNING)
+	if o.FileService != nil {
+		if err := o.FileService.CreateWorkDir(); err != nil {
+			o.Logger.Log(logger.ERROR, "%v", err)
+		}
+	}
+	if o.resuming {
+		o.journal(JournalEntry{Type: JOURNAL_RESUME, OperationId: o.OperationID, Adversary: o.Adversary.Name})
+	} else {
+		o.journal(JournalEntry{Type: JOURNAL_START, OperationId: o.OperationID, Adversary: o.Adversary.Name})
+	}
+	o.Logger.Log(logger.TRACE, "Running operation %s with %s planner", o.Name, o.Planner.Name())
 	fmt.Println(colorprint.ColorString("\n------------------------ EXPLOIT PHASE ------------------------", colorprint.YELLOW))
-	for index, ability_id := range o.Adversary.AtomicOrdering {
-		if ability, exists := o.Abilities[ability_id]; exists {
-			if ability.IsAvailable(o.shells) {
-				fmt.Println(colorprint.ColorString(fmt.Sprintf("\n[+] Running ability (%d/%d) %s", index, len(o.Adversary.AtomicOrdering), ability.Name), colorprint.YELLOW))
-				fmt.Println(colorprint.ColorString(fmt.Sprintf("    [-] %s: %s(%s)", ability.Tactic, ability.Technique, ability.TechniqueId), colorprint.YELLOW))
-				links, cleanupLinks := ability.CreateLinks(o.Logger, o.shells, o.Facts)
-				o.Links = append(o.Links, links...)
-				o.Logger.Log(logger.TRACE, "Creating links of ability %s", ability.Name)
-				o.CleanupLinks = append(o.CleanupLinks, cleanupLinks...)
-				for _, link := range links {
-					link.Execute(o.ExecutingServices[link.Executor.Name])
-					o.attireLog.AddLinkResult(&link)
-					o.attireLog.DumpToFile("log.json")
-					if !o.Cleanup {
-						secondclass.DumpLinksToJson(o.CleanupLinks, "cleanups.json", o.Logger)
-					}
-				}
-			}
+	o.Planner.Execute(o)
+	o.collectBackground("")
+	if o.Stopping() {
+		o.Logger.Log(logger.WARN, "Operation (%s - %s) stopped, %d cleanup links queued", o.Name, o.OperationID, len(o.CleanupLinks))
+	} else {
+		o.ignoreUnlinkedAbilities()
+		o.Logger.Log(logger.INFO, "Operation (%s - %s) successfully executed!", o.Name, o.OperationID)
+	}
+	o.dumpAttireLog("log.json")
+	if o.writes(OUTPUT_FACTS) {
+		if err := o.KnowledgeService.DumpToFile(o.outputPath("facts.json")); err != nil {
+			o.Logger.Log(logger.ERROR, "Failed to dump operation knowledge: %v", err)
 		}
 	}
-	o.Logger.Log(logger.INFO, "Operation (%s - %s) successfully executed!", o.Name, o.OperationID)
 	if o.Cleanup {
 		fmt.Println(colorprint.ColorString("\n------------------------ CLEANUP PHASE ------------------------", colorprint.YELLOW))
 		o.CleanupOperation()
 	}
+	o.PrintSummary()
+	if !o.Stopping() {
+		o.journal(JournalEntry{Type: JOURNAL_FINISH})
+	}
+	o.setStatus(FINISHED)
+}
+
+// Stop asks the operation to stop: the running link is killed, no new link
+// starts and the run continues with the cleanup phase.
+func (o *Operation) Stop() {
+	o.mutex.Lock()
+	defer o.mutex.Unlock()
+	o.Status = WAITING_TO_STOP
+	o.cancel()
+}
+
+// Stopping reports whether Stop was called.
+func (o *Operation) Stopping() bool {
+	o.mutex.Lock()
+	defer o.mutex.Unlock()
+	return o.Status == WAITING_TO_STOP
+}
+
+func (o *Operation) setStatus(status int) {
+	o.mutex.Lock()
+	defer o.mutex.Unlock()
+	o.Status = status
+}
+
+// PrintSummary prints the outcome of the links and the abilities that were ignored.
+func (o *Operation) PrintSummary() {
+	statuses := map[int64]int{}
+	for _, link := range o.Links {
+		statuses[link.Status]++
+	}
+	fmt.Println(colorprint.ColorString("\n------------------------ SUMMARY ------------------------", colorprint.YELLOW))
+	fmt.Printf("[+] Links: %d (success: %d, error: %d, timeout: %d, unavailable: %d, skipped: %d, interrupted: %d)\n", len(o.Links),
+		statuses[secondclass.SUCCESS], statuses[secondclass.ERROR], statuses[secondclass.TIMEOUT], statuses[secondclass.UNAVAILABLE],
+		statuses[secondclass.DISCARD], statuses[secondclass.INTERRUPTED])
+	o.printIgnored()
+}
+
+func (o *Operation) printIgnored() {
+	fmt.Printf("[+] Ignored abilities: %d\n", len(o.Ignored))
+	for _, ignored := range o.Ignored {
+		fmt.Println(colorprint.ColorString(fmt.Sprintf("    [-] %s [%s] %s", o.abilityLabel(ignored.AbilityId, ignored.Name), ignored.Reason, ignored.Detail), colorprint.YELLOW))
+	}
+}
+
+// AvailableAbilities returns the abilities of the adversary that can run on this
+// agent, in atomic ordering, with their position in that ordering.
+func (o *Operation) AvailableAbilities() ([]int, []Ability) {
+	indexes, abilities := []int{}, []Ability{}
+	for index, ability_id := range o.Adversary.AtomicOrdering {
+		if ability, available := o.isAvailable(ability_id); available {
+			indexes = append(indexes, index)
+			abilities = append(abilities, ability)
+		}
+	}
+	return indexes, abilities
+}
+
+// PendingLinks returns the links of an ability that have not been executed yet,
+// together with their cleanup links. Repeatable abilities always return all links.
+// Links that finished before the operation was resumed are used up instead of
+// being returned, so it is only called for links about to run; see HasNewLinks.
+func (o *Operation) PendingLinks(ability Ability) ([]secondclass.Link, []secondclass.Link) {
+	o.Logger.Log(logger.TRACE, "Creating links of ability %s", ability.Name)
+	links, cleanupLinks := ability.CreateLinks(o.Logger, o.os, o.usableShells(), o.ExecutorPreference)
+	pending := []secondclass.Link{}
+	for _, link := range links {
+		if o.finished[linkSignature(&link)] > 0 {
+			// Ran before the operation was resumed
+			o.finished[linkSignature(&link)]--
+			continue
+		}
+		if ability.Repeatable || !o.executed[linkSignature(&link)] {
+			pending = append(pending, link)
+		}
+	}
+	return pending, cleanupLinks
+}
+
+// HasNewLinks reports whether an ability has a link that has not run in the
+// operation, including before it was resumed. Planners run until no ability has
+// one: a repeatable ability runs its links again whenever it is run, but its
+// links that ran before do not keep a planner going. It does not use up the
+// links finished before a resume, unlike PendingLinks.
+func (o *Operation) HasNewLinks(ability Ability) bool {
+	links, _ := ability.CreateLinks(o.Logger, o.os, o.usableShells(), o.ExecutorPreference)
+	for _, link := range links {
+		if !o.executed[linkSignature(&link)] {
+			return true
+		}
+	}
+	return false
+}
+
+// RunAbility executes the pending links of an ability and returns how many of
+// them had not run before, see HasNewLinks.
+// A link whose executor turns out to be unavailable is retried with the next
+// eligible executor of the ability. Background links that stop after the ability
+// are collected once it ran.
+func (o *Operation) RunAbility(index int, ability Ability) int {
+	defer o.collectBackground(ability.AbilityId)
+	if o.Stopping() {
+		return 0
+	}
+	o.journal(JournalEntry{Type: JOURNAL_ABILITY, Index: index, AbilityId: ability.AbilityId})
+	links, cleanupLinks := o.PendingLinks(ability)
+	if len(links) == 0 {
+		o.Logger.Log(logger.DEBUG, "No new links for ability %s", ability.Name)
+		return 0
+	}
+	newLinks := 0
+	for _, link := range links {
+		if !o.executed[linkSignature(&link)] {
+			newLinks++
+		}
+	}
+	if o.dryRun {
+		o.planLinks(links, cleanupLinks)
+		return newLinks
+	}
+	fmt.Println(colorprint.ColorString(fmt.Sprintf("\n[+] Running ability (%d/%d) %s", index, len(o.Adversary.AtomicOrdering), ability.Name), colorprint.YELLOW))
+	fmt.Println(colorprint.ColorString(fmt.Sprintf("    [-] %s: %s(%s)", ability.Tactic, ability.Technique, ability.TechniqueId), colorprint.YELLOW))
+	for _, link := range links {
+		if o.Stopping() {
+			break
+		}
+		linkCleanupLinks := cleanupLinks
+		o.executed[linkSignature(&link)] = true
+		if o.approve(&link) {
+			o.executeLink(ability, &link)
+		}
+		for link.Status == secondclass.UNAVAILABLE {
+			if !link.Executor.IsCode() {
+				o.unavailable[link.Executor.Name] = true
+			}
+			fallback, fallbackCleanupLinks := ability.FallbackLink(o.Logger, &link, o.os, o.usableShells(), o.ExecutorPreference)
+			if fallback == nil {
+				o.Logger.Log(logger.WARN, "No executor left for ability %s after trying %v", ability.Name, link.ExecutorsTried)
+				break
+			}
+			o.Logger.Log(logger.WARN, "Executor %s unavailable for ability %s, falling back to %s", link.Executor.Name, ability.Name, fallback.Executor.Name)
+			link, linkCleanupLinks = *fallback, fallbackCleanupLinks
+			o.executed[linkSignature(&link)] = true
+			if o.approve(&link) {
+				o.executeLink(ability, &link)
+			}
+		}
+		if link.Status != secondclass.UNAVAILABLE && link.Status != secondclass.DISCARD {
+			o.addCleanupLinks(linkCleanupLinks)
+		}
+		if link.Status == secondclass.EXECUTE {
+			// Running in the background, recorded once collected
+			continue
+		}
+		o.recordLink(&link)
+	}
+	return newLinks
+}
+
+// recordLink adds a finished link to the operation: its facts are learned and
+// its result is written to the ATTiRe log.
+func (o *Operation) recordLink(link *secondclass.Link) {
+	o.Links = append(o.Links, *link)
+	o.KnowledgeService.AddUsage(link.LinkId, link.Used)
+	o.learnFacts(link)
+	o.journal(JournalEntry{Type: JOURNAL_LINK, Link: link})
+	o.attireLog.AddLinkResult(link)
+	o.dumpAttireLog("log.json")
+	if !o.Cleanup {
+		secondclass.DumpLinksToJson(o.CleanupLinks, o.outputPath("cleanups.json"), o.Logger)
+	}
+}
+
+// approve asks the Approver whether a link runs when the operation is not
+// autonomous. Links that do not run are discarded; quitting stops the operation.
+func (o *Operation) approve(link *secondclass.Link) bool {
+	if o.Autonomous {
+		return true
+	}
+	approval := o.Approver.Approve(link)
+	link.Approval = &approval
+	switch approval.Decision {
+	case secondclass.APPROVED:
+		o.Logger.Log(logger.INFO, "Operator approved link of ability %s", link.ProcedureName)
+		return true
+	case secondclass.EDITED:
+		o.Logger.Log(logger.INFO, "Operator edited link of ability %s: %s", link.ProcedureName, link.Command)
+		return true
+	case secondclass.QUIT:
+		o.Logger.Log(logger.WARN, "Operator stopped the operation at ability %s", link.ProcedureName)
+		o.Stop()
+	default:
+		o.Logger.Log(logger.INFO, "Operator skipped link of ability %s", link.ProcedureName)
+	}
+	link.Decide()
+	link.Finish()
+	link.Status = secondclass.DISCARD
+	return false
+}
+
+// executeLink stages the payloads of a link, runs it and collects its uploads.
+// Background links are finished when they are collected.
+func (o *Operation) executeLink(ability Ability, link *secondclass.Link) {
+	if o.unavailable[link.Executor.Name] {
+		link.Decide()
+		link.Finish()
+		link.ExecutorsTried = append(link.ExecutorsTried, link.Executor.Name)
+		link.Status = secondclass.UNAVAILABLE
+		link.ExitCode = -1
+		link.Error = fmt.Sprintf("%s: %s", execute.ErrExecutorUnavailable, link.Executor.Name)
+		return
+	}
+	if err := o.stagePayloads(link.Executor.Payloads); err != nil {
+		o.Logger.Log(logger.ERROR, "Failed to stage payloads of ability %s: %v", ability.Name, err)
+		link.Decide()
+		link.Finish()
+		link.Status = secondclass.ERROR
+		link.ExitCode = -1
+		link.Error = err.Error()
+		return
+	}
+	if link.Executor.Background {
+		if o.startBackground(ability, link) {
+			return
+		}
+	} else {
+		o.runLink(o.ctx, link)
+	}
+	o.finishLink(ability, link)
+}
+
+// finishLink removes the payloads of a finished link when its ability asks for
+// it and collects its uploads.
+func (o *Operation) finishLink(ability Ability, link *secondclass.Link) {
+	if ability.DeletePayload && len(link.Executor.Payloads) > 0 {
+		o.FileService.RemovePayloads(link.Executor.Payloads)
+	}
+	if link.Status != secondclass.UNAVAILABLE {
+		o.collectUploads(link)
+	}
+}
+
+// privilegeAllows decides whether an ability can run with the privileges of the
+// agent. The decision is logged and recorded in the ATTiRe log once per ability.
+func (o *Operation) privilegeAllows(ability Ability) bool {
+	if !ability.RequiresElevation() {
+		return true
+	}
+	if decision, decided := o.privilegeDecisions[ability.AbilityId]; decided {
+		return decision.Decision != PRIVILEGE_SKIPPED
+	}
+	decision := PrivilegeDecision{
+		Required: ability.Privilege,
+		Elevated: o.Privilege.Elevated,
+		Decision: PRIVILEGE_RUN,
+		Reason:   o.Privilege.Detail,
+	}
+	switch {
+	case o.Privilege.Elevated:
+		o.Logger.Log(logger.DEBUG, "Ability %s requires elevation, agent is elevated (%s)", ability.Name, o.Privilege.Detail)
+	case o.ForcePrivileged:
+		decision.Decision = PRIVILEGE_FORCED
+		o.Logger.Log(logger.WARN, "Ability %s requires elevation but the agent is not elevated (%s), running anyway", ability.Name, o.Privilege.Detail)
+	default:
+		decision.Decision = PRIVILEGE_SKIPPED
+		o.ignore(ability.AbilityId, ability.Name, IGNORED_PRIVILEGE, "requires elevation but the agent is not elevated ("+o.Privilege.Detail+")")
+	}
+	o.privilegeDecisions[ability.AbilityId] = decision
+	o.attireLog.RecordPrivilege(&ability, decision)
+	return decision.Decision != PRIVILEGE_SKIPPED
+}
+
+// UsePrivilege sets the privileges the agent runs with and whether elevated
+// abilities run without them.
+func (o *Operation) UsePrivilege(privilege envdetector.Privilege, force bool) {
+	o.Privilege = privilege
+	o.ForcePrivileged = force
+	o.attireLog.ExecutionData["privilege"] = privilege
+}
+
+// usableShells returns the detected shells whose executors have not proven unavailable.
+func (o *Operation) usableShells() []string {
+	shells := []string{}
+	for _, shell := range o.shells {
+		if !o.unavailable[shell] {
+			shells = append(shells, shell)
+		}
+	}
+	return shells
+}
+
+// addCleanupLinks queues cleanup links that are not queued yet.
+func (o *Operation) addCleanupLinks(cleanupLinks []secondclass.Link) {
+	for _, link := range cleanupLinks {
+		if !o.executed[linkSignature(&link)] {
+			o.executed[linkSignature(&link)] = true
+			o.mutex.Lock()
+			o.CleanupLinks = append(o.CleanupLinks, link)
+			o.mutex.Unlock()
+			o.journal(JournalEntry{Type: JOURNAL_CLEANUP, Link: &link})
+		}
+	}
+}
 
+// linkSignature identifies a link by what it runs rather than by its random ID.
+func linkSignature(link *secondclass.Link) string {
+	return fmt.Sprintf("%s|%s|%t|%s|%s", link.ProcedureId, link.Executor.Name, link.IsCleanup, link.Command, link.Stdin)
 }
 
 func (o *Operation) CleanupOperation() {
@@ -93,81 +459,292 @@ func (o *Operation) CleanupOperation() {
 	for i := len(o.CleanupLinks) - 1; i >= 0; i-- {
 		link := o.CleanupLinks[i]
 		o.Logger.Log(logger.INFO, "Cleaning up link of ability %s(%s)", link.ProcedureName, link.MitreTechniqueId)
-		link.Execute(o.ExecutingServices[link.Executor.Name])
+		if o.approve(&link) {
+			o.runLink(context.Background(), &link)
+		}
 		o.attireLog.AddLinkResult(&link)
-		o.attireLog.DumpToFile("log.json")
+		o.dumpAttireLog("log.json")
+		if link.Approval != nil && link.Approval.Decision == secondclass.QUIT {
+			o.Logger.Log(logger.WARN, "Remaining cleanup links saved to %s, run the cleanup command to clean them up", o.outputPath("cleanups.json"))
+			secondclass.DumpLinksToJson(o.CleanupLinks[:i+1], o.outputPath("cleanups.json"), o.Logger)
+			return
+		}
+		o.journal(JournalEntry{Type: JOURNAL_CLEANED, Link: &link})
+		o.mutex.Lock()
+		o.cleaned++
+		o.mutex.Unlock()
 	}
 	o.Logger.Log(logger.INFO, "Operation (%s - %s) cleanup successfully executed!", o.Name, o.OperationID)
 }
-func NewOperation(adversary Adversary, autonomous, cleanup bool, abilities []Ability, shells []string, os string, ip string, log *logger.Logger, knowledgeService *knowledge.KnowledgeService) *Operation {
+
+// SavePendingCleanup writes the cleanup links that did not run yet to
+// cleanups.json for the cleanup command and returns the path. It can be called
+// while the operation runs, e.g. before the process exits on a signal.
+func (o *Operation) SavePendingCleanup() string {
+	o.mutex.Lock()
+	pending := slices.Clone(o.CleanupLinks[:len(o.CleanupLinks)-o.cleaned])
+	o.mutex.Unlock()
+	path := o.outputPath("cleanups.json")
+	secondclass.DumpLinksToJson(pending, path, o.Logger)
+	return path
+}
+
+// NewOperation builds the operation of an adversary on the agent it runs on,
+// with the settings, planner and file service of its config.
+func NewOperation(config *OperationConfig, adversary Adversary, abilities []Ability, agent Agent, log *logger.Logger, knowledgeService *knowledge.KnowledgeService) (*Operation, error) {
+	if err := config.Validate(); err != nil {
+		return nil, err
+	}
+	planner, err := NewPlanner(config.Planner)
+	if err != nil {
+		return nil, err
+	}
 	operation := Operation{
-		OperationID:       uuid.New().String(),
-		Name:              adversary.Name,
-		Adversary:         adversary,
-		Autonomous:        autonomous,
-		Cleanup:           cleanup,
-		Abilities:         map[string]Ability{},
-		Source:            Source{Logger: log},
-		Facts:             map[string][]*secondclass.Fact{},
-		Links:             []secondclass.Link{},
-		CleanupLinks:      []secondclass.Link{},
-		Ignored:           []Ability{},
-		Logger:            log,
-		Status:            FINISHED,
-		shells:            shells,
-		os:                os,
-		attireLog:         *NewAttireLog(ip),
-		ExecutingServices: map[string]execute.ExecutingService{},
-		KnowledgeService:  knowledgeService,
+		OperationID:           uuid.New().String(),
+		Name:                  adversary.Name,
+		Adversary:             adversary,
+		Autonomous:            config.Autonomous,
+		Cleanup:               config.Cleanup == CLEANUP_RUN,
+		Abilities:             map[string]Ability{},
+		Source:                Source{Logger: log},
+		Links:                 []secondclass.Link{},
+		CleanupLinks:          []secondclass.Link{},
+		Ignored:               []IgnoredAbility{},
+		Logger:                log,
+		Status:                FINISHED,
+		Planner:               planner,
+		Approver:              NewTerminalApprover(),
+		StreamOutput:          config.Stream,
+		OutputLimit:           config.OutputLimit,
+		ExecutorPreference:    config.Executors,
+		OutputDir:             config.OutputDir,
+		Outputs:               config.Outputs,
+		Jitter:                config.Jitter,
+		DefaultTimeout:        time.Duration(config.Timeout),
+		DefaultCleanupTimeout: time.Duration(config.CleanupTimeout),
+		Allow:                 config.Allow,
+		Deny:                  config.Deny,
+		executed:              map[string]bool{},
+		finished:              map[string]int{},
+		unavailable:           map[string]bool{},
+		privilegeDecisions:    map[string]PrivilegeDecision{},
+		shells:                agent.Shells,
+		os:                    agent.OS,
+		attireLog:             *NewAttireLog(agent.IP),
+		ExecutingServices:     map[string]execute.ExecutingService{},
+		KnowledgeService:      knowledgeService,
 	}
+	operation.ctx, operation.cancel = context.WithCancel(context.Background())
+	operation.UsePrivilege(agent.Privilege, config.ForcePrivileged)
 	operation.AddAbilities(abilities)
 	operation.addingExecutingServices()
-	operation.Source.LoadFromYAML("data/source.yml")
-	operation.addingFacts()
-	return &operation
+	fileService, err := file.NewFileService(config.PayloadsDir(), config.PayloadURL, config.WorkDir, config.ArtifactsPath(), config.UploadURL, log)
+	if err != nil {
+		return nil, fmt.Errorf("failed to initialize file service: %w", err)
+	}
+	operation.UseFileService(fileService)
+	return &operation, nil
+}
+
+// Agent is the host an operation runs on.
+type Agent struct {
+	OS        string
+	Shells    []string
+	IP        string
+	Privilege envdetector.Privilege
+}
+
+// NewOperationFromConfig loads the abilities, adversary and fact sources of the
+// data paths of a config and builds their operation.
+func NewOperationFromConfig(config *OperationConfig, agent Agent, log *logger.Logger) (*Operation, error) {
+	knowledgeService := knowledge.NewKnowledgeService(log)
+	abilities, err := LoadRunnableAbilities(config.AbilitiesDir(), log, knowledgeService)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load abilities: %w", err)
+	}
+	adversary := NewAdversaryWithLogger(log)
+	if err := adversary.LoadFromYAML(config.AdversaryFile()); err != nil {
+		return nil, err
+	}
+	operation, err := NewOperation(config, *adversary, abilities, agent, log, knowledgeService)
+	if err != nil {
+		return nil, err
+	}
+	if err := operation.LoadSources(config.SourceFiles()); err != nil {
+		return nil, fmt.Errorf("failed to load fact sources: %w", err)
+	}
+	return operation, nil
 }
 
-func NewCleanupOperation(cleanupLinks []secondclass.Link, shells []string, os, ip string, log *logger.Logger) *Operation {
+// NewCleanupOperation builds the operation that runs the cleanup links an
+// operation saved, writing its results to the output directory of the config.
+func NewCleanupOperation(config *OperationConfig, cleanupLinks []secondclass.Link, agent Agent, log *logger.Logger) *Operation {
 	operation := Operation{
 		OperationID:       uuid.New().String(),
 		Name:              "Cleanup Operation",
+		StreamOutput:      true,
 		CleanupLinks:      cleanupLinks,
-		Ignored:           []Ability{},
+		Ignored:           []IgnoredAbility{},
 		Logger:            log,
-		shells:            shells,
-		os:                os,
-		attireLog:         *NewAttireLog(ip),
+		OutputDir:         config.OutputDir,
+		Outputs:           config.Outputs,
+		shells:            agent.Shells,
+		os:                agent.OS,
+		attireLog:         *NewAttireLog(agent.IP),
 		ExecutingServices: map[string]execute.ExecutingService{},
 	}
+	operation.ctx, operation.cancel = context.WithCancel(context.Background())
 	operation.addingExecutingServices()
 	return &operation
 }
 
 func (o *Operation) RunningCleanupOperation() {
 	o.Logger.Log(logger.TRACE, "Running cleanup operation")
-	for i := len(o.CleanupLinks) - 1; i >= 0; i-- {
+	for i := len(o.CleanupLinks) - 1; i >= 0 && !o.Stopping(); i-- {
 		link := o.CleanupLinks[i]
 		o.Logger.Log(logger.INFO, "Running cleanup link of ability %s(%s)", link.ProcedureName, link.MitreTechniqueId)
-		link.Execute(o.ExecutingServices[link.Executor.Name])
+		o.runLink(o.ctx, &link)
 		o.attireLog.AddLinkResult(&link)
-		o.attireLog.DumpToFile("cleanup_log.json")
+		o.dumpAttireLog("cleanup_log.json")
 
-		o.CleanupLinks = append(o.CleanupLinks[:i], o.CleanupLinks[i+1:]...)
-		secondclass.DumpLinksToJson(o.CleanupLinks, "not_completed_cleanups.json", o.Logger)
+		if link.Status != secondclass.INTERRUPTED {
+			o.mutex.Lock()
+			o.CleanupLinks = append(o.CleanupLinks[:i], o.CleanupLinks[i+1:]...)
+			o.mutex.Unlock()
+		}
+		secondclass.DumpLinksToJson(o.CleanupLinks, o.outputPath("not_completed_cleanups.json"), o.Logger)
+	}
+	if o.Stopping() {
+		o.Logger.Log(logger.WARN, "Cleanup operation stopped, remaining links saved to %s", o.outputPath("not_completed_cleanups.json"))
+		return
 	}
 	o.Logger.Log(logger.INFO, "Cleanup operation successfully executed!")
 }
 
-func (o *Operation) addingFacts() {
-	for _, fact := range o.Source.Facts {
-		if facts, exists := o.Facts[fact.Trait]; exists {
-			o.Facts[fact.Trait] = append(facts, &fact)
-		} else {
-			o.Facts[fact.Trait] = []*secondclass.Fact{&fact}
+// LoadSources adds the facts and relationships of source files to the knowledge
+// of the operation. The first source is the source of the operation.
+func (o *Operation) LoadSources(paths []string) error {
+	for i, path := range paths {
+		source := Source{Logger: o.Logger}
+		if err := source.LoadFromYAML(path); err != nil {
+			return err
+		}
+		if i == 0 {
+			o.Source = source
 		}
+		o.addingFacts(&source)
 	}
+	return nil
 }
 
+func (o *Operation) addingFacts(source *Source) {
+	for i := range source.Facts {
+		fact := &source.Facts[i]
+		if fact.Source == "" {
+			fact.Source = source.Id
+		}
+		o.KnowledgeService.AddFact(fact)
+	}
+	for i := range source.Relationships {
+		o.KnowledgeService.AddRelationship(&source.Relationships[i])
+	}
+}
+
+// outputPath returns the path of a file the operation writes in its output directory.
+func (o *Operation) outputPath(name string) string {
+	return filepath.Join(o.OutputDir, name)
+}
+
+// writes reports whether the operation writes an output, all of them when
+// Outputs is not set.
+func (o *Operation) writes(output string) bool {
+	return o.Outputs == nil || slices.Contains(o.Outputs, output)
+}
+
+func (o *Operation) dumpAttireLog(name string) {
+	if o.writes(OUTPUT_ATTIRE) {
+		o.attireLog.DumpToFile(o.outputPath(name))
+	}
+}
+
+// learnFacts runs the parsers of the link executor against its output and adds
+// the discovered facts and relationships to the knowledge service.
+func (o *Operation) learnFacts(link *secondclass.Link) {
+	if link.Status != secondclass.SUCCESS || len(link.Executor.Parsers) == 0 {
+		return
+	}
+	relationships := parser.ParseOutput(link.Out, link.Executor.Parsers, o.Logger)
+	for _, relationship := range relationships {
+		o.learnFact(relationship.Source, link)
+		if relationship.Target == nil {
+			continue
+		}
+		o.learnFact(relationship.Target, link)
+		relationship.Origin = secondclass.LEARNED
+		if o.KnowledgeService.AddRelationship(&relationship) {
+			o.journal(JournalEntry{Type: JOURNAL_RELATIONSHIP, Relationship: &relationship})
+		}
+	}
+}
+
+func (o *Operation) learnFact(fact *secondclass.Fact, link *secondclass.Link) {
+	learned := secondclass.NewLearnedFact(fact.Trait, fact.Value, link.LinkId, link.MitreTechniqueId)
+	if _, isNew := o.KnowledgeService.AddFact(learned); isNew {
+		o.Logger.Log(logger.INFO, "Learned fact #{%s} = %s", fact.Trait, fact.Value)
+		o.journal(JournalEntry{Type: JOURNAL_FACT, Fact: learned})
+	}
+}
+
+// collectUploads collects the files named in the uploads of the link executor,
+// with the facts used by the link substituted into their paths.
+func (o *Operation) collectUploads(link *secondclass.Link) {
+	if len(link.Executor.Uploads) == 0 || o.FileService == nil {
+		return
+	}
+	paths := []string{}
+	for _, upload := range link.Executor.Uploads {
+		for _, combination := range o.KnowledgeService.ReplaceFactsWithUsed(upload, link.Used) {
+			paths = append(paths, combination.Command)
+		}
+	}
+	link.Uploads = o.FileService.UploadFiles(paths, o.OperationID, link.LinkId)
+}
+
+func (o *Operation) stagePayloads(payloads []string) error {
+	if len(payloads) == 0 {
+		return nil
+	}
+	if o.FileService == nil {
+		return fmt.Errorf("no file service available to stage payloads %v", payloads)
+	}
+	return o.FileService.StagePayloads(payloads)
+}
+
+// executingService returns the service a link runs with: a code executor for
+// inline code, otherwise the shell named by the link executor.
+func (o *Operation) executingService(link *secondclass.Link) execute.ExecutingService {
+	if link.Executor.IsCode() && !link.IsCleanup {
+		code := execute.NewCode(link.Executor.CodeLanguage(), link.Executor.BuildTarget, o.Logger)
+		if o.FileService != nil {
+			code.SetWorkingDir(o.FileService.WorkDir)
+		}
+		return code
+	}
+	return o.ExecutingServices[link.Executor.Name]
+}
+
+// UseFileService sets the file service of the operation and makes every
+// executing service run from its working directory.
+func (o *Operation) UseFileService(fileService *file.FileService) {
+	o.FileService = fileService
+	for _, executingService := range o.ExecutingServices {
+		executingService.SetWorkingDir(fileService.WorkDir)
+	}
+}
+
+// ExecutorNames lists the executors commands can run with, on any platform.
+var ExecutorNames = []string{"psh", "cmd", "sh", "bash", "zsh", "python", "python3", "perl", "pwsh"}
+
 func (o *Operation) addingExecutingServices() {
 	if o.os == "windows" {
 		if slices.Contains(o.shells, "psh") {
@@ -180,5 +757,23 @@ func (o *Operation) addingExecutingServices() {
 		if slices.Contains(o.shells, "sh") {
 			o.ExecutingServices["sh"] = execute.NewSh(o.Logger)
 		}
+		if slices.Contains(o.shells, "bash") {
+			o.ExecutingServices["bash"] = execute.NewBash(o.Logger)
+		}
+		if slices.Contains(o.shells, "zsh") {
+			o.ExecutingServices["zsh"] = execute.NewZsh(o.Logger)
+		}
+		if slices.Contains(o.shells, "python") {
+			o.ExecutingServices["python"] = execute.NewPython("python", o.Logger)
+		}
+		if slices.Contains(o.shells, "python3") {
+			o.ExecutingServices["python3"] = execute.NewPython("python3", o.Logger)
+		}
+		if slices.Contains(o.shells, "perl") {
+			o.ExecutingServices["perl"] = execute.NewPerl(o.Logger)
+		}
+		if slices.Contains(o.shells, "pwsh") {
+			o.ExecutingServices["pwsh"] = execute.NewPwsh(o.Logger)
+		}
 	}
 }
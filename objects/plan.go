@@ -0,0 +1,131 @@
+package objects
+
+import (
+	"calderat/secondclass"
+	"calderat/utils/colorprint"
+	"encoding/json"
+	"fmt"
+	"os"
+	"strings"
+)
+
+// Phases of a planned link.
+const (
+	PHASE_EXPLOIT = "exploit"
+	PHASE_CLEANUP = "cleanup"
+)
+
+// PlannedLink is a link a dry run would execute, with the command resolved from
+// the facts of the sources.
+type PlannedLink struct {
+	Order       int      `json:"order"`
+	Phase       string   `json:"phase"`
+	AbilityId   string   `json:"ability-id"`
+	Name        string   `json:"name"`
+	Tactic      string   `json:"tactic"`
+	TechniqueId string   `json:"technique-id"`
+	Executor    string   `json:"executor"`
+	Command     string   `json:"command"`
+	Stdin       string   `json:"stdin,omitempty"`
+	Timeout     Duration `json:"timeout"`
+	Background  bool     `json:"background,omitempty"`
+	Payloads    []string `json:"payloads,omitempty"`
+	Facts       []string `json:"facts,omitempty"` // trait=value of the facts filled in
+}
+
+// Plan runs the planner without executing anything and returns the links the
+// operation would run in order, followed by its cleanup links in the order the
+// cleanup phase runs them. Abilities waiting for facts that only earlier links
+// would learn cannot be planned and are recorded as ignored. Nothing is written
+// to the journal, the output directory or the working directory.
+func (o *Operation) Plan() []PlannedLink {
+	o.setStatus(RUNNING)
+	o.dryRun = true
+	o.Journal = nil
+	o.Planner.Execute(o)
+	o.ignoreUnlinkedAbilities()
+	for i := len(o.CleanupLinks) - 1; i >= 0; i-- {
+		link := o.CleanupLinks[i]
+		o.planLink(&link, PHASE_CLEANUP)
+	}
+	o.setStatus(FINISHED)
+	return o.plan
+}
+
+// planLinks records the links of an ability in the plan instead of running them.
+func (o *Operation) planLinks(links []secondclass.Link, cleanupLinks []secondclass.Link) {
+	for i := range links {
+		o.executed[linkSignature(&links[i])] = true
+		o.planLink(&links[i], PHASE_EXPLOIT)
+	}
+	o.addCleanupLinks(cleanupLinks)
+}
+
+func (o *Operation) planLink(link *secondclass.Link, phase string) {
+	o.applyDefaults(link)
+	facts := []string{}
+	for _, fact := range link.Used {
+		facts = append(facts, fact.Trait+"="+fact.Value)
+	}
+	stdin := ""
+	if link.Executor.Stdin != "" {
+		stdin = link.Stdin
+	}
+	o.plan = append(o.plan, PlannedLink{
+		Order:       len(o.plan) + 1,
+		Phase:       phase,
+		AbilityId:   link.ProcedureId,
+		Name:        link.ProcedureName,
+		Tactic:      o.Abilities[link.ProcedureId].Tactic,
+		TechniqueId: link.MitreTechniqueId,
+		Executor:    link.Executor.Name,
+		Command:     link.Command,
+		Stdin:       stdin,
+		Timeout:     Duration(link.Timeout),
+		Background:  link.Executor.Background && phase == PHASE_EXPLOIT,
+		Payloads:    link.Executor.Payloads,
+		Facts:       facts,
+	})
+}
+
+// PrintPlan prints the planned links for an operator to approve, with the
+// abilities that will not run.
+func (o *Operation) PrintPlan(plan []PlannedLink) {
+	phase := ""
+	for _, planned := range plan {
+		if planned.Phase != phase {
+			phase = planned.Phase
+			fmt.Println(colorprint.ColorString(fmt.Sprintf("\n------------------------ %s PHASE (DRY RUN) ------------------------", strings.ToUpper(phase)), colorprint.YELLOW))
+		}
+		timeout := planned.Timeout.String()
+		if planned.Background && planned.Timeout <= 0 {
+			timeout = "none"
+		}
+		fmt.Println(colorprint.ColorString(fmt.Sprintf("\n[%d] %s (%s, %s) with %s, timeout %s", planned.Order, planned.Name, planned.Tactic, planned.TechniqueId, planned.Executor, timeout), colorprint.YELLOW))
+		fmt.Printf("    %s\n", strings.ReplaceAll(planned.Command, "\n", "\n    "))
+		if planned.Stdin != "" {
+			fmt.Printf("    stdin: %q\n", planned.Stdin)
+		}
+		if planned.Background {
+			fmt.Println(colorprint.ColorString("    runs in the background", colorprint.CYAN))
+		}
+		if len(planned.Payloads) > 0 {
+			fmt.Printf("    payloads: %s\n", strings.Join(planned.Payloads, ", "))
+		}
+		if len(planned.Facts) > 0 {
+			fmt.Printf("    facts: %s\n", strings.Join(planned.Facts, ", "))
+		}
+	}
+	fmt.Println(colorprint.ColorString("\n------------------------ PLAN ------------------------", colorprint.YELLOW))
+	fmt.Printf("[+] Links: %d planned, nothing was executed\n", len(plan))
+	o.printIgnored()
+}
+
+// WritePlan exports the planned links to a JSON file.
+func WritePlan(plan []PlannedLink, filename string) error {
+	data, err := json.MarshalIndent(plan, "", "  ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(filename, data, 0644)
+}
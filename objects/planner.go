@@ -0,0 +1,50 @@
+package objects
+
+import (
+	"calderat/utils/logger"
+	"fmt"
+	"strings"
+)
+
+const (
+	// MaxPlannerRounds bounds planners that repeat until no new links appear,
+	// in case parsers keep producing new facts.
+	MaxPlannerRounds = 50
+)
+
+// Planner decides in which order the abilities of an operation run.
+type Planner interface {
+	Name() string
+	Execute(o *Operation)
+}
+
+// NewPlanner returns the planner registered under the given name.
+func NewPlanner(name string) (Planner, error) {
+	switch strings.ToLower(strings.ReplaceAll(name, "-", "_")) {
+	case "", "atomic":
+		return NewAtomicPlanner(), nil
+	case "batch":
+		return NewBatchPlanner(), nil
+	case "buckets":
+		return NewBucketsPlanner(nil), nil
+	case "look_ahead", "lookahead":
+		return NewLookAheadPlanner(), nil
+	default:
+		return nil, fmt.Errorf("unknown planner: %s", name)
+	}
+}
+
+// runRounds runs every ability in rounds until a round executes no new link.
+// Repeatable abilities run again every round, see Operation.HasNewLinks.
+func runRounds(o *Operation, indexes []int, abilities []Ability) {
+	for round := 1; round <= MaxPlannerRounds; round++ {
+		executed := 0
+		for i, ability := range abilities {
+			executed += o.RunAbility(indexes[i], ability)
+		}
+		if executed == 0 || o.Stopping() {
+			return
+		}
+	}
+	o.Logger.Log(logger.WARN, "Planner stopped after %d rounds", MaxPlannerRounds)
+}
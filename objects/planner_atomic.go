@@ -0,0 +1,24 @@
+package objects
+
+// AtomicPlanner walks the atomic ordering of the adversary once, from top to bottom.
+type AtomicPlanner struct {
+	name string
+}
+
+func NewAtomicPlanner() *AtomicPlanner {
+	return &AtomicPlanner{name: "atomic"}
+}
+
+func (p *AtomicPlanner) Name() string {
+	return p.name
+}
+
+func (p *AtomicPlanner) Execute(o *Operation) {
+	indexes, abilities := o.AvailableAbilities()
+	for i, ability := range abilities {
+		if o.Stopping() {
+			return
+		}
+		o.RunAbility(indexes[i], ability)
+	}
+}
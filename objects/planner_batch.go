@@ -0,0 +1,20 @@
+package objects
+
+// BatchPlanner runs every runnable ability each round and repeats until a round
+// produces no new links, so abilities whose facts are discovered later still run.
+type BatchPlanner struct {
+	name string
+}
+
+func NewBatchPlanner() *BatchPlanner {
+	return &BatchPlanner{name: "batch"}
+}
+
+func (p *BatchPlanner) Name() string {
+	return p.name
+}
+
+func (p *BatchPlanner) Execute(o *Operation) {
+	indexes, abilities := o.AvailableAbilities()
+	runRounds(o, indexes, abilities)
+}
@@ -0,0 +1,77 @@
+package objects
+
+import (
+	"calderat/utils/logger"
+	"slices"
+)
+
+// DefaultBucketOrder is the phase order of the buckets planner, following the
+// ATT&CK tactics from initial access to impact.
+var DefaultBucketOrder = []string{
+	"reconnaissance",
+	"resource-development",
+	"initial-access",
+	"execution",
+	"persistence",
+	"privilege-escalation",
+	"defense-evasion",
+	"credential-access",
+	"discovery",
+	"lateral-movement",
+	"collection",
+	"command-and-control",
+	"exfiltration",
+	"impact",
+}
+
+// BucketsPlanner groups abilities by their first bucket (or tactic) and walks the
+// buckets in phase order. Inside a bucket abilities run in rounds, like the batch
+// planner, before the next bucket starts. Unknown buckets run last.
+type BucketsPlanner struct {
+	name  string
+	order []string
+}
+
+func NewBucketsPlanner(order []string) *BucketsPlanner {
+	if len(order) == 0 {
+		order = DefaultBucketOrder
+	}
+	return &BucketsPlanner{name: "buckets", order: order}
+}
+
+func (p *BucketsPlanner) Name() string {
+	return p.name
+}
+
+func (p *BucketsPlanner) Execute(o *Operation) {
+	indexes, abilities := o.AvailableAbilities()
+	buckets := slices.Clone(p.order)
+	for _, ability := range abilities {
+		if bucket := abilityBucket(ability); !slices.Contains(buckets, bucket) {
+			buckets = append(buckets, bucket)
+		}
+	}
+	for _, bucket := range buckets {
+		if o.Stopping() {
+			return
+		}
+		bucketIndexes, bucketAbilities := []int{}, []Ability{}
+		for i, ability := range abilities {
+			if abilityBucket(ability) == bucket {
+				bucketIndexes = append(bucketIndexes, indexes[i])
+				bucketAbilities = append(bucketAbilities, ability)
+			}
+		}
+		if len(bucketAbilities) > 0 {
+			o.Logger.Log(logger.INFO, "Entering bucket %s (%d abilities)", bucket, len(bucketAbilities))
+			runRounds(o, bucketIndexes, bucketAbilities)
+		}
+	}
+}
+
+func abilityBucket(ability Ability) string {
+	if len(ability.Buckets) > 0 {
+		return ability.Buckets[0]
+	}
+	return ability.Tactic
+}
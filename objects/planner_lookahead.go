@@ -0,0 +1,83 @@
+package objects
+
+import "slices"
+
+const (
+	LookAheadDepth    = 3
+	LookAheadDiscount = 0.9
+	DefaultReward     = 1.0
+)
+
+// LookAheadPlanner repeatedly runs the ability with new links with the highest expected
+// reward: its own reward plus the discounted reward of the abilities it unlocks
+// through the facts its parsers produce, up to LookAheadDepth levels.
+type LookAheadPlanner struct {
+	name     string
+	depth    int
+	discount float64
+}
+
+func NewLookAheadPlanner() *LookAheadPlanner {
+	return &LookAheadPlanner{name: "look_ahead", depth: LookAheadDepth, discount: LookAheadDiscount}
+}
+
+func (p *LookAheadPlanner) Name() string {
+	return p.name
+}
+
+func (p *LookAheadPlanner) Execute(o *Operation) {
+	indexes, abilities := o.AvailableAbilities()
+	for step := 0; step < MaxPlannerRounds*len(abilities) && !o.Stopping(); step++ {
+		best, bestReward := -1, -1.0
+		for i, ability := range abilities {
+			if !o.HasNewLinks(ability) {
+				continue
+			}
+			if reward := p.reward(i, abilities, p.depth, map[int]bool{}); reward > bestReward {
+				best, bestReward = i, reward
+			}
+		}
+		if best < 0 {
+			return
+		}
+		o.RunAbility(indexes[best], abilities[best])
+	}
+}
+
+// reward computes the expected reward of running an ability.
+func (p *LookAheadPlanner) reward(index int, abilities []Ability, depth int, visited map[int]bool) float64 {
+	reward := DefaultReward
+	if depth == 0 {
+		return reward
+	}
+	visited[index] = true
+	future := 0.0
+	for i := range abilities {
+		if visited[i] || !unlocks(abilities[index], abilities[i]) {
+			continue
+		}
+		future = max(future, p.reward(i, abilities, depth-1, visited))
+	}
+	delete(visited, index)
+	return reward + p.discount*future
+}
+
+// unlocks reports whether the parsers of one ability produce a trait the commands of another require.
+func unlocks(from, to Ability) bool {
+	produced := []string{}
+	for _, executor := range from.Executors {
+		for _, p := range executor.Parsers {
+			for _, config := range p.ParserConfigs {
+				produced = append(produced, config.Source, config.Target)
+			}
+		}
+	}
+	for _, executor := range to.Executors {
+		for _, trait := range to.KnowledgeService.RequiredTraits(executor.Body() + "\n" + executor.Stdin) {
+			if trait != "" && slices.Contains(produced, trait) {
+				return true
+			}
+		}
+	}
+	return false
+}
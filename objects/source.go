@@ -10,8 +10,11 @@ import (
 )
 
 type Source struct {
-	Facts  []secondclass.Fact `yaml:"facts"`
-	Logger *logger.Logger
+	Id            string                     `yaml:"id"`
+	Name          string                     `yaml:"name"`
+	Facts         []secondclass.Fact         `yaml:"facts"`
+	Relationships []secondclass.Relationship `yaml:"relationships"`
+	Logger        *logger.Logger
 }
 
 func NewSource(facts []secondclass.Fact, log *logger.Logger) *Source {
@@ -0,0 +1,105 @@
+package objects
+
+import (
+	"calderat/secondclass"
+	"calderat/service/execute"
+	"calderat/utils/colorprint"
+	"calderat/utils/logger"
+	"context"
+	"encoding/base64"
+	"fmt"
+	"time"
+)
+
+// ProgressInterval is how long a link may run without output before a progress line is printed.
+const ProgressInterval = 10 * time.Second
+
+// LinkOutput is a line of output of a running link, sent on Operation.Events.
+type LinkOutput struct {
+	LinkId        string
+	ProcedureName string
+	execute.Line
+}
+
+// runLink executes a link while its output is streamed line by line to the
+// console and to the Events channel. Binary output is spilled to an artifact.
+func (o *Operation) runLink(ctx context.Context, link *secondclass.Link) {
+	o.applyDefaults(link)
+	lines := make(chan execute.Line)
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		o.streamLines(link, lines)
+	}()
+	link.Execute(ctx, o.executingService(link), lines)
+	close(lines)
+	<-done
+	o.spillBinaryOutput(link, &link.Out, &link.OutCapture, "stdout.bin")
+	o.spillBinaryOutput(link, &link.Err, &link.ErrCapture, "stderr.bin")
+}
+
+// applyDefaults gives a link the settings of the operation it does not set
+// itself, and the jitter of the operation when it has one.
+func (o *Operation) applyDefaults(link *secondclass.Link) {
+	if link.Executor.OutputLimit == 0 {
+		link.Executor.OutputLimit = o.OutputLimit
+	}
+	// A background link without a timeout runs until it is collected
+	if link.Timeout == 0 && (link.IsCleanup || !link.Executor.Background) {
+		if link.IsCleanup && o.DefaultCleanupTimeout > 0 {
+			link.Timeout = o.DefaultCleanupTimeout
+		} else if o.DefaultTimeout > 0 {
+			link.Timeout = o.DefaultTimeout
+		}
+	}
+	if o.Jitter.Defined() {
+		link.Jitter = o.Jitter.Pick()
+	}
+}
+
+// spillBinaryOutput moves binary output of a link out of the logs into a file
+// in the artifacts directory. Without a file service it stays base64-encoded.
+func (o *Operation) spillBinaryOutput(link *secondclass.Link, output *string, capture *execute.Capture, name string) {
+	if !capture.Binary || o.FileService == nil {
+		return
+	}
+	data, err := base64.StdEncoding.DecodeString(*output)
+	if err == nil {
+		capture.File, err = o.FileService.SaveArtifact(data, name, o.OperationID, link.LinkId)
+	}
+	if err != nil {
+		o.Logger.Log(logger.WARN, "Failed to spill binary output of %s, keeping it base64-encoded: %v", link.ProcedureName, err)
+		return
+	}
+	o.Logger.Log(logger.INFO, "Binary output of %s (%d bytes) saved to %s", link.ProcedureName, len(data), capture.File)
+	*output = ""
+}
+
+func (o *Operation) streamLines(link *secondclass.Link, lines <-chan execute.Line) {
+	progress := time.NewTicker(ProgressInterval)
+	defer progress.Stop()
+	for {
+		select {
+		case line, ok := <-lines:
+			if !ok {
+				return
+			}
+			progress.Reset(ProgressInterval)
+			if o.StreamOutput {
+				if line.Stream == execute.STDERR {
+					fmt.Println(colorprint.ColorString("    ! "+line.Text, colorprint.RED))
+				} else {
+					fmt.Println("    | " + line.Text)
+				}
+			}
+			if o.Events != nil {
+				o.Events <- LinkOutput{LinkId: link.LinkId, ProcedureName: link.ProcedureName, Line: line}
+			}
+		case <-progress.C:
+			if o.StreamOutput && !link.DecidedTime.IsZero() {
+				elapsed := time.Since(link.DecidedTime).Round(time.Second)
+				fmt.Println(colorprint.ColorString(fmt.Sprintf("    [~] %s still running after %s (timeout %s)", link.ProcedureName, elapsed, link.Timeout), colorprint.CYAN))
+			}
+		}
+	}
+}
@@ -0,0 +1,73 @@
+package main
+
+import (
+	"calderat/objects"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"os"
+	"text/tabwriter"
+)
+
+// report prints a summary of the ATTiRe log an operation wrote in the output
+// directory: every step with its exit code, then the abilities ignored.
+func report(args []string) int {
+	logFile := ""
+	config, err := parseConfig("report", args, func(flags *flag.FlagSet, config *objects.OperationConfig) {
+		bindOutput(flags, config)
+		flags.StringVar(&logFile, "log", "log.json", "ATTiRe log in the output directory, e.g. cleanup_log.json")
+	})
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 2
+	}
+
+	path := config.OutputPath(logFile)
+	raw, err := os.ReadFile(path)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Failed to read %s: %v\n", path, err)
+		return 1
+	}
+	var attireLog objects.AttireLog
+	if err := json.Unmarshal(raw, &attireLog); err != nil {
+		fmt.Fprintf(os.Stderr, "Failed to parse %s: %v\n", path, err)
+		return 1
+	}
+
+	if target, ok := attireLog.ExecutionData["target"].(map[string]interface{}); ok {
+		fmt.Printf("Target %v (%v) as %v, log generated %v\n\n", target["host"], target["ip"], target["user"], attireLog.ExecutionData["time-generated"])
+	}
+
+	steps, failed, cleanups := 0, 0, 0
+	writer := tabwriter.NewWriter(os.Stdout, 0, 4, 2, ' ', 0)
+	fmt.Fprintf(writer, "#\tTECHNIQUE\tPROCEDURE\tSTEP\tEXECUTOR\tEXIT\tERROR\n")
+	for _, procedure := range attireLog.Procedures {
+		for _, step := range procedure.Steps {
+			steps++
+			if step.ExitCode != 0 || step.Error != "" {
+				failed++
+			}
+			fmt.Fprintf(writer, "%d\t%s\t%s\t%d\t%s\t%d\t%s\n", procedure.Order, procedure.MitreTechniqueId, procedure.ProcedureName, step.Order, step.Executor, step.ExitCode, step.Error)
+		}
+		for _, step := range procedure.CleanupCommands {
+			cleanups++
+			fmt.Fprintf(writer, "%d\t%s\t%s\tcleanup\t%s\t%d\t%s\n", procedure.Order, procedure.MitreTechniqueId, procedure.ProcedureName, step.Executor, step.ExitCode, step.Error)
+		}
+		if procedure.Privilege != nil && procedure.Privilege.Decision == objects.PRIVILEGE_SKIPPED {
+			fmt.Fprintf(writer, "%d\t%s\t%s\t-\t-\t-\t%s\n", procedure.Order, procedure.MitreTechniqueId, procedure.ProcedureName, procedure.Privilege.Reason)
+		}
+	}
+	writer.Flush()
+	fmt.Printf("\n%d procedures, %d steps, %d failed, %d cleanup commands\n", len(attireLog.Procedures), steps, failed, cleanups)
+
+	if len(attireLog.Ignored) > 0 {
+		fmt.Printf("\n%d abilities ignored:\n", len(attireLog.Ignored))
+		writer = tabwriter.NewWriter(os.Stdout, 0, 4, 2, ' ', 0)
+		fmt.Fprintf(writer, "ABILITY\tNAME\tREASON\tDETAIL\n")
+		for _, ignored := range attireLog.Ignored {
+			fmt.Fprintf(writer, "%s\t%s\t%s\t%s\n", ignored.AbilityId, ignored.Name, ignored.Reason, ignored.Detail)
+		}
+		writer.Flush()
+	}
+	return 0
+}
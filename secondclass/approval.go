@@ -0,0 +1,18 @@
+package secondclass
+
+import "time"
+
+// Operator decisions on a link in manual mode.
+const (
+	APPROVED = "approve"
+	SKIPPED  = "skip"
+	EDITED   = "edit"
+	QUIT     = "quit"
+)
+
+// Approval records the operator decision on a link before it ran.
+type Approval struct {
+	Decision        string    `json:"decision"`
+	OriginalCommand string    `json:"original-command,omitempty"`
+	Time            time.Time `json:"time"`
+}
@@ -1,17 +1,59 @@
 package secondclass
 
+import (
+	"strings"
+	"time"
+)
+
+// Platforms executors can target, named after runtime.GOOS.
+const (
+	LINUX   = "linux"
+	WINDOWS = "windows"
+	DARWIN  = "darwin"
+)
+
+// Platforms lists the known platforms in display order.
+var Platforms = []string{LINUX, WINDOWS, DARWIN}
+
+// platformAliases maps the platform names found in Caldera and Atomic Red Team
+// abilities to their runtime.GOOS name.
+var platformAliases = map[string]string{
+	"linux":   LINUX,
+	"win":     WINDOWS,
+	"windows": WINDOWS,
+	"darwin":  DARWIN,
+	"mac":     DARWIN,
+	"macos":   DARWIN,
+	"osx":     DARWIN,
+}
+
 type Executor struct {
-	Name     string   `json:"name"`
-	Platform string   `json:"platform"`
-	Command  string   `json:"command"`
-	Code     string   `json:"code"`
-	Payloads []string `json:"payloads"`
-	Uploads  []string `json:"upload"`
-	Timeout  int      `json:"timeout"`
-	Cleanup  []string `json:"cleanup"`
+	Name        string   `json:"name"`
+	Platform    string   `json:"platform"`
+	Command     string   `json:"command"`
+	Code        string   `json:"code"`
+	Language    string   `json:"language"`
+	BuildTarget string   `json:"build_target" yaml:"build_target"`
+	Payloads    []string `json:"payloads"`
+	Uploads     []string `json:"upload"`
+	Timeout     int      `json:"timeout"`
+	Cleanup     []string `json:"cleanup"`
+	Parsers     []Parser `json:"parsers"`
+	// OutputLimit is the number of bytes of each output stream kept, 0 for the operation default
+	OutputLimit int `json:"output_limit" yaml:"output_limit"`
+	// Encoding of the command output when it cannot be detected, e.g. cp850
+	Encoding string `json:"encoding"`
+	// Stdin is written to the command, facts are substituted in it like in the command
+	Stdin string `json:"stdin"`
+	// TTY runs the command on a pseudo-terminal for tools that insist on one
+	TTY bool `json:"tty"`
+	// Background starts the command and moves on; it is stopped and its output
+	// collected once the ability StopAfter ran, or at the end of the operation
+	Background bool   `json:"background"`
+	StopAfter  string `json:"stop_after" yaml:"stop_after"`
 }
 
-func NewExecutor(name string, platform string, command string, code string, payloads []string, uploads []string, timeout int, cleanup []string) *Executor {
+func NewExecutor(name string, platform string, command string, code string, payloads []string, uploads []string, timeout int, cleanup []string, parsers []Parser) *Executor {
 	return &Executor{
 		Name:     name,
 		Platform: platform,
@@ -21,5 +63,64 @@ func NewExecutor(name string, platform string, command string, code string, payl
 		Uploads:  uploads,
 		Timeout:  timeout,
 		Cleanup:  cleanup,
+		Parsers:  parsers,
+	}
+}
+
+// IsCode reports whether the executor runs inline code instead of a command.
+func (e *Executor) IsCode() bool {
+	return e.Command == "" && e.Code != ""
+}
+
+// Body returns the command of the executor, or its code for code executors.
+func (e *Executor) Body() string {
+	if e.IsCode() {
+		return e.Code
+	}
+	return e.Command
+}
+
+// CodeLanguage returns the language of the inline code, defaulting to the executor name.
+func (e *Executor) CodeLanguage() string {
+	if e.Language != "" {
+		return e.Language
+	}
+	return e.Name
+}
+
+// BackgroundCleanupTimeout bounds the cleanup of a background executor without
+// a timeout, whose command itself runs until it is stopped.
+const BackgroundCleanupTimeout = time.Minute
+
+// CleanupTimeout returns the timeout of the cleanup commands of the executor.
+func (e *Executor) CleanupTimeout() time.Duration {
+	if e.Background && e.Timeout <= 0 {
+		return BackgroundCleanupTimeout
+	}
+	return time.Duration(e.Timeout) * time.Second
+}
+
+// NormalizePlatform returns the runtime.GOOS name of a platform or one of its aliases.
+// Unknown platforms are returned lowercased.
+func NormalizePlatform(platform string) string {
+	platform = strings.ToLower(strings.TrimSpace(platform))
+	if normalized, ok := platformAliases[platform]; ok {
+		return normalized
+	}
+	return platform
+}
+
+// RunsOn reports whether the executor targets the platform. The executor platform
+// may list several platforms separated by commas; an empty platform matches any.
+func (e *Executor) RunsOn(platform string) bool {
+	if strings.TrimSpace(e.Platform) == "" {
+		return true
+	}
+	platform = NormalizePlatform(platform)
+	for _, target := range strings.Split(e.Platform, ",") {
+		if NormalizePlatform(target) == platform {
+			return true
+		}
 	}
+	return false
 }
@@ -1,9 +1,27 @@
 package secondclass
 
+import "time"
+
+// Origin types of a fact, following Caldera's naming.
+const (
+	IMPORTED = "IMPORTED"
+	LEARNED  = "LEARNED"
+	USER     = "USER"
+	SEEDED   = "SEEDED"
+)
+
 type Fact struct {
-	Value  string `yaml:"value"`
-	Trait  string `yaml:"trait"`
-	Unique string `yaml:"unique"`
+	Value       string    `yaml:"value" json:"value"`
+	Trait       string    `yaml:"trait" json:"trait"`
+	Unique      string    `yaml:"unique" json:"unique"`
+	Score       int       `yaml:"score" json:"score"`
+	Origin      string    `yaml:"origin_type" json:"origin_type"`
+	Source      string    `yaml:"source" json:"source"`
+	TechniqueId string    `yaml:"technique_id" json:"technique_id"`
+	CollectedBy []string  `yaml:"collected_by" json:"collected_by"`
+	Links       []string  `yaml:"links" json:"links"`
+	Created     time.Time `yaml:"created" json:"created"`
+	Updated     time.Time `yaml:"updated" json:"updated"`
 }
 
 func NewFact(trait string, value string) *Fact {
@@ -13,3 +31,21 @@ func NewFact(trait string, value string) *Fact {
 		Unique: trait + value,
 	}
 }
+
+// NewLearnedFact creates a fact collected from the output of a link.
+func NewLearnedFact(trait, value, linkId, techniqueId string) *Fact {
+	fact := NewFact(trait, value)
+	fact.Origin = LEARNED
+	fact.Score = 1
+	fact.TechniqueId = techniqueId
+	fact.CollectedBy = []string{linkId}
+	return fact
+}
+
+// Equal compares two facts by trait and value.
+func (f *Fact) Equal(other *Fact) bool {
+	if f == nil || other == nil {
+		return f == other
+	}
+	return f.Trait == other.Trait && f.Value == other.Value
+}
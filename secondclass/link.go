@@ -4,10 +4,12 @@ import (
 	"calderat/service/execute"
 	"calderat/utils/logger"
 	"calderat/utils/random"
+	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
+	"math"
 	"os"
-	"strings"
 	"time"
 
 	"github.com/google/uuid"
@@ -19,7 +21,11 @@ const (
 	PAUSED  = -1
 	SUCCESS = 0
 	ERROR   = 1
-	TIMEOUT = 128
+	// UNAVAILABLE marks links whose executor could not run on this host
+	UNAVAILABLE = 127
+	TIMEOUT     = 128
+	// INTERRUPTED marks links killed because the operation was stopped
+	INTERRUPTED = 130
 )
 
 type Link struct {
@@ -28,16 +34,25 @@ type Link struct {
 	MitreTechniqueId string `json:"mitre-technique-id"`
 	LinkId           string `json:"link-id"`
 	Command          string `json:"command"`
+	Stdin            string `json:"stdin,omitempty"` // Written to the command, with facts substituted
 	Status           int64
 	Jitter           time.Duration `json:"jitter"`
 	Executor         Executor      `json:"executor"`
 	DecidedTime      time.Time
 	FinishedTime     time.Time
-	Out              string
-	Err              string
-	Timeout          time.Duration `json:"timeout"`
-	IsCleanup        bool          `json:"is-cleanup"`
-	Logger           *logger.Logger
+	Out              string          // Standard output of the command
+	Err              string          // Standard error of the command
+	OutCapture       execute.Capture `json:"stdout-capture"` // How the output was truncated and decoded
+	ErrCapture       execute.Capture `json:"stderr-capture"`
+	ExitCode         int             `json:"exit-code"`
+	Error            string          `json:"error,omitempty"` // Why the link failed, if it did
+	Timeout          time.Duration   `json:"timeout"`
+	IsCleanup        bool            `json:"is-cleanup"`
+	Used             []*Fact         `json:"used"`
+	Uploads          []Upload        `json:"uploads"`
+	ExecutorsTried   []string        `json:"executors-tried"`
+	Approval         *Approval       `json:"approval,omitempty"`
+	Logger           *logger.Logger  `json:"-"`
 }
 
 func NewLink(procedureName, procedureId, mitreTechniqueId, command string, executor Executor, timeout time.Duration, log *logger.Logger, isCleanup bool) *Link {
@@ -59,17 +74,110 @@ func NewLink(procedureName, procedureId, mitreTechniqueId, command string, execu
 	return &link
 }
 
-func (link *Link) Execute(executingService execute.ExecutingService) {
+// Execute runs the link with the executing service. Cancelling the context
+// interrupts the jitter wait or kills the running command. When lines is set,
+// the output is sent on it line by line while the command runs.
+func (link *Link) Execute(ctx context.Context, executingService execute.ExecutingService, lines chan<- execute.Line) {
+	if !link.prepare(ctx, executingService) {
+		return
+	}
+	request := link.request()
+	request.Lines = lines
+	result, err := executingService.Execute(ctx, request)
+	link.complete(result, err)
+}
+
+// Start runs the link in the background with the executing service and returns
+// the handle of the command once it started. The link is finished by Collect.
+// Start returns nil when the command did not start; the link is finished then.
+// A background link without a timeout runs until it is collected.
+func (link *Link) Start(ctx context.Context, executingService execute.ExecutingService) *execute.Handle {
+	if !link.prepare(ctx, executingService) {
+		return nil
+	}
+	request := link.request()
+	if request.Timeout <= 0 {
+		request.Timeout = time.Duration(math.MaxInt64)
+	}
+	handle := execute.Start(ctx, executingService, request)
+	if !handle.Running() {
+		link.complete(handle.Wait())
+		return nil
+	}
+	link.Logger.Log(logger.INFO, "Started %s in the background (pid %d)", link.ProcedureName, handle.Pid)
+	return handle
+}
+
+// Collect stops the background command of the link unless it already ended and
+// records its output. A command stopped by Collect ran as intended and succeeds.
+func (link *Link) Collect(handle *execute.Handle) {
+	result, err := handle.Stop()
+	if handle.Stopped() && errors.Is(err, execute.ErrInterrupted) {
+		link.Logger.Log(logger.INFO, "Stopped background process %d of %s", result.Pid, link.ProcedureName)
+		err = nil
+	}
+	link.complete(result, err)
+}
+
+// prepare waits for the jitter and reports whether the link can run. Otherwise
+// the link is finished as unavailable or interrupted.
+func (link *Link) prepare(ctx context.Context, executingService execute.ExecutingService) bool {
 	link.Logger.Log(logger.INFO, "Waiting for %s", link.Jitter)
-	time.Sleep(link.Jitter)
+	select {
+	case <-time.After(link.Jitter):
+	case <-ctx.Done():
+	}
 	link.Decide()
-	output, err := executingService.Execute(link.Command, link.Timeout)
+	link.ExecutorsTried = append(link.ExecutorsTried, link.Executor.Name)
+	if executingService == nil {
+		link.Finish()
+		link.ExitCode = -1
+		link.Error = fmt.Sprintf("%s: no executing service for %s", execute.ErrExecutorUnavailable, link.Executor.Name)
+		link.Status = UNAVAILABLE
+		return false
+	}
+	if ctx.Err() != nil {
+		link.Finish()
+		link.ExitCode = -1
+		link.Error = fmt.Sprintf("%s: %v", execute.ErrInterrupted, ctx.Err())
+		link.Status = INTERRUPTED
+		return false
+	}
+	return true
+}
+
+func (link *Link) request() execute.Request {
+	return execute.Request{
+		Command:     link.Command,
+		Timeout:     link.Timeout,
+		Stdin:       link.Stdin,
+		TTY:         link.Executor.TTY,
+		OutputLimit: link.Executor.OutputLimit,
+		Encoding:    link.Executor.Encoding,
+	}
+}
+
+// complete finishes the link with the result of its command.
+func (link *Link) complete(result execute.Result, err error) {
 	link.Finish()
 	link.Logger.Log(logger.INFO, "Command finished after %s\n", link.Duration())
-	link.Out = output
+	link.Logger.Log(logger.DEBUG, "Process %d exited with code %d %s", result.Pid, result.ExitCode, result.Signal)
+	link.Out = result.Stdout
+	link.Err = result.Stderr
+	link.OutCapture = result.StdoutCapture
+	link.ErrCapture = result.StderrCapture
+	if link.OutCapture.Truncated || link.ErrCapture.Truncated {
+		link.Logger.Log(logger.WARN, "Output truncated: %d of %d stdout bytes and %d of %d stderr bytes omitted",
+			link.OutCapture.Omitted, link.OutCapture.Bytes, link.ErrCapture.Omitted, link.ErrCapture.Bytes)
+	}
+	link.ExitCode = result.ExitCode
 	if err != nil {
-		link.Err = err.Error()
-		if strings.Contains(err.Error(), "timeout") {
+		link.Error = err.Error()
+		if errors.Is(err, execute.ErrExecutorUnavailable) {
+			link.Status = UNAVAILABLE
+		} else if errors.Is(err, execute.ErrInterrupted) {
+			link.Status = INTERRUPTED
+		} else if errors.Is(err, execute.ErrTimeout) {
 			link.Status = TIMEOUT
 		} else {
 			link.Status = ERROR
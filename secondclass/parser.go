@@ -0,0 +1,24 @@
+package secondclass
+
+// Parser describes how the output of an executor is turned into facts.
+// Module selects the parsing service (regex, line, json, keyvalue) and every
+// ParserConfig produces facts for its source trait and, optionally, a
+// relationship to a target trait.
+type Parser struct {
+	Module        string         `json:"module" yaml:"module"`
+	ParserConfigs []ParserConfig `json:"parserconfigs" yaml:"parserconfigs"`
+}
+
+type ParserConfig struct {
+	Source           string            `json:"source" yaml:"source"`
+	Edge             string            `json:"edge" yaml:"edge"`
+	Target           string            `json:"target" yaml:"target"`
+	CustomParserVals map[string]string `json:"custom_parser_vals" yaml:"custom_parser_vals"`
+}
+
+func NewParser(module string, parserConfigs []ParserConfig) *Parser {
+	return &Parser{
+		Module:        module,
+		ParserConfigs: parserConfigs,
+	}
+}
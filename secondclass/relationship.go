@@ -0,0 +1,25 @@
+package secondclass
+
+// Relationship links a source fact to an optional target fact through an edge,
+// e.g. host.user.name -has_password-> host.user.password.
+type Relationship struct {
+	Source *Fact  `json:"source" yaml:"source"`
+	Edge   string `json:"edge" yaml:"edge"`
+	Target *Fact  `json:"target" yaml:"target"`
+	Score  int    `json:"score" yaml:"score"`
+	Origin string `json:"origin" yaml:"origin"`
+}
+
+func NewRelationship(source *Fact, edge string, target *Fact) *Relationship {
+	return &Relationship{
+		Source: source,
+		Edge:   edge,
+		Target: target,
+		Score:  1,
+	}
+}
+
+// Equal compares two relationships by their facts and edge.
+func (r *Relationship) Equal(other *Relationship) bool {
+	return r.Edge == other.Edge && r.Source.Equal(other.Source) && r.Target.Equal(other.Target)
+}
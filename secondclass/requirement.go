@@ -0,0 +1,22 @@
+package secondclass
+
+// Requirement gates the fact combinations an ability may run with.
+// Module names a requirement service (e.g. plugins.stockpile.app.requirements.paired)
+// and every RelationshipMatch must be satisfied for a link to be created.
+type Requirement struct {
+	Module            string              `json:"module" yaml:"module"`
+	RelationshipMatch []RelationshipMatch `json:"relationship_match" yaml:"relationship_match"`
+}
+
+type RelationshipMatch struct {
+	Source string `json:"source" yaml:"source"`
+	Edge   string `json:"edge" yaml:"edge"`
+	Target string `json:"target" yaml:"target"`
+}
+
+func NewRequirement(module string, relationshipMatch []RelationshipMatch) *Requirement {
+	return &Requirement{
+		Module:            module,
+		RelationshipMatch: relationshipMatch,
+	}
+}
@@ -0,0 +1,10 @@
+package secondclass
+
+// Upload records a file collected from the host after a link finished.
+type Upload struct {
+	Path        string `json:"path"`
+	Sha256      string `json:"sha256,omitempty"`
+	Size        int64  `json:"size"`
+	Destination string `json:"destination,omitempty"`
+	Error       string `json:"error,omitempty"`
+}
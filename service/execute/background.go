@@ -0,0 +1,72 @@
+package execute
+
+import (
+	"context"
+	"sync/atomic"
+)
+
+// Handle is a command running in the background, started by Start.
+type Handle struct {
+	Pid     int // Process id, 0 when the command did not start
+	cancel  context.CancelFunc
+	done    chan struct{}
+	stopped atomic.Bool
+	result  Result
+	err     error
+}
+
+// Start runs a request with the executing service in the background. It returns
+// once the command started, or once it ended when it could not start. Cancelling
+// the context kills the command like Stop does.
+func Start(ctx context.Context, executingService ExecutingService, request Request) *Handle {
+	ctx, cancel := context.WithCancel(ctx)
+	handle := &Handle{cancel: cancel, done: make(chan struct{})}
+	started := make(chan int, 1)
+	request.Started = func(pid int) { started <- pid }
+	go func() {
+		defer close(handle.done)
+		defer cancel()
+		handle.result, handle.err = executingService.Execute(ctx, request)
+	}()
+	select {
+	case handle.Pid = <-started:
+	case <-handle.done:
+	}
+	return handle
+}
+
+// Done is closed once the command ended.
+func (h *Handle) Done() <-chan struct{} {
+	return h.done
+}
+
+// Running reports whether the command has not ended yet.
+func (h *Handle) Running() bool {
+	select {
+	case <-h.done:
+		return false
+	default:
+		return true
+	}
+}
+
+// Stop kills the command and every process it started unless it already ended,
+// then returns how it ended. A stopped command ends with ErrInterrupted.
+func (h *Handle) Stop() (Result, error) {
+	if h.Running() {
+		h.stopped.Store(true)
+		h.cancel()
+	}
+	return h.Wait()
+}
+
+// Stopped reports whether the command was killed by Stop.
+func (h *Handle) Stopped() bool {
+	return h.stopped.Load()
+}
+
+// Wait waits for the command to end and returns how it ended.
+func (h *Handle) Wait() (Result, error) {
+	<-h.done
+	return h.result, h.err
+}
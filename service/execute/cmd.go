@@ -1,20 +1,18 @@
 package execute
 
 import (
-	"calderat/utils/colorprint"
 	"calderat/utils/logger"
 	"context"
 	"fmt"
-	"os/exec"
 	"runtime"
 	"strings"
-	"time"
 )
 
 type Cmd struct {
 	shortName string
 	logger    *logger.Logger
 	path      string
+	dir       string
 }
 
 func parseWindowsCmd(command string) ([]string, error) {
@@ -56,47 +54,27 @@ func NewCmd(log *logger.Logger) *Cmd {
 	}
 }
 
-// Execute runs a CMD command with a specified timeout
-func (ce *Cmd) Execute(command string, timeout time.Duration) (string, error) {
+// Execute runs a CMD command with the request timeout
+func (ce *Cmd) Execute(ctx context.Context, request Request) (Result, error) {
 	if runtime.GOOS != "windows" {
 		ce.logger.Log(logger.ERROR, "Command execution failed: Unsupported OS")
-		return "", fmt.Errorf("%s is only supported on Windows systems", ce.shortName)
+		return Result{ExitCode: -1}, fmt.Errorf("%w: %s is only supported on Windows systems", ErrExecutorUnavailable, ce.shortName)
 	}
 
-	ce.logger.Log(logger.INFO, "Executing command: %s by %s (Timeout: %v)", command, ce.shortName, timeout)
+	ce.logger.Log(logger.INFO, "Executing command: %s by %s (Timeout: %v)", request.Command, ce.shortName, request.Timeout)
 
-	// Create a context with the specified timeout
-	ctx, cancel := context.WithTimeout(context.Background(), timeout)
-	defer cancel()
 	// Use shlex to split the command string into arguments.
-	parsedArgs, err := parseWindowsCmd(command)
+	parsedArgs, err := parseWindowsCmd(request.Command)
 	if err != nil {
 		ce.logger.Log(logger.ERROR, "Error parsing command: %v", err)
-		return "", fmt.Errorf("error parsing command: %v", err)
+		return Result{ExitCode: -1}, fmt.Errorf("error parsing command: %v", err)
 	}
 
 	// Prepend "/C" so that cmd.exe executes the command and then exits.
 	args := append([]string{"/C"}, parsedArgs...)
 
-	// Construct the execution command with the parsed arguments.
-	cmd := exec.CommandContext(ctx, ce.path, args...)
-
-	// Capture the output
-	output, err := cmd.CombinedOutput()
-
-	// Check for context timeout
-	if ctx.Err() == context.DeadlineExceeded {
-		ce.logger.Log(logger.WARN, "Command timed out after %v", timeout)
-		return "", fmt.Errorf("command timed out after %v", timeout)
-	}
-
-	if err != nil {
-		fmt.Println(colorprint.ColorString(fmt.Sprintf("Command execution failed: %v\nOutput: %s", err, string(output)), colorprint.RED))
-		return "", fmt.Errorf("failed to execute %s command: %v\nOutput: %s", ce.shortName, err, string(output))
-	}
-
-	ce.logger.Log(logger.DEBUG, "Command executed successfully. Output:\n%s", string(output))
-	return string(output), nil
+	result, err := process{path: ce.path, args: args, dir: ce.dir, codePage: consoleCodePage()}.run(ctx, request)
+	return report(ce.logger, ce.shortName, result, err)
 }
 
 func (ce *Cmd) ShortName() string {
@@ -106,3 +84,8 @@ func (ce *Cmd) ShortName() string {
 func (ce *Cmd) Path() string {
 	return ce.path
 }
+
+// SetWorkingDir sets the directory commands run from (empty means the current directory)
+func (ce *Cmd) SetWorkingDir(dir string) {
+	ce.dir = dir
+}
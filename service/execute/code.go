@@ -0,0 +1,170 @@
+package execute
+
+import (
+	"calderat/utils/logger"
+	"context"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// Interpreter describes how a script of a given language is written to disk and run.
+type Interpreter struct {
+	Extension string   // File extension of the script
+	Paths     []string // Candidate interpreters, the first found in PATH is used
+	Args      []string // Arguments placed before the script path
+}
+
+var interpreters = map[string]Interpreter{
+	"sh":     {Extension: ".sh", Paths: []string{"sh"}},
+	"bash":   {Extension: ".sh", Paths: []string{"bash"}},
+	"zsh":    {Extension: ".zsh", Paths: []string{"zsh"}},
+	"python": {Extension: ".py", Paths: []string{"python3", "python"}},
+	"perl":   {Extension: ".pl", Paths: []string{"perl"}},
+	"psh":    {Extension: ".ps1", Paths: []string{"powershell", "pwsh"}, Args: []string{"-ExecutionPolicy", "Bypass", "-File"}},
+	"pwsh":   {Extension: ".ps1", Paths: []string{"pwsh"}, Args: []string{"-NoProfile", "-NonInteractive", "-File"}},
+	"cmd":    {Extension: ".bat", Paths: []string{"cmd.exe"}, Args: []string{"/C"}},
+	"go":     {Extension: ".go", Paths: []string{"go"}, Args: []string{"run"}},
+}
+
+// KnownLanguage reports whether inline code of the language can be run.
+func KnownLanguage(language string) bool {
+	_, known := interpreters[NormalizeLanguage(language)]
+	return known
+}
+
+// Code runs the inline code of an executor: the code is written to a temporary
+// file and run with the interpreter of its language. Go code with a build target
+// is compiled with the local toolchain first. Every file is removed afterwards.
+type Code struct {
+	shortName   string
+	language    string
+	buildTarget string
+	logger      *logger.Logger
+	dir         string
+}
+
+// NewCode initializes a new code executor for the given language
+// (sh, bash, zsh, python, perl, psh, pwsh, cmd or go).
+func NewCode(language, buildTarget string, log *logger.Logger) *Code {
+	return &Code{
+		shortName:   "code",
+		language:    NormalizeLanguage(language),
+		buildTarget: buildTarget,
+		logger:      log,
+	}
+}
+
+// NormalizeLanguage maps language aliases to the interpreter names known to Code.
+func NormalizeLanguage(language string) string {
+	language = strings.ToLower(strings.TrimSpace(language))
+	switch language {
+	case "python3", "py":
+		return "python"
+	case "powershell":
+		return "psh"
+	case "golang":
+		return "go"
+	}
+	return language
+}
+
+// Execute writes the code of the request to a temporary file and runs it within
+// the request timeout, which covers the build for Go code with a build target
+func (ce *Code) Execute(ctx context.Context, request Request) (Result, error) {
+	interpreter, exists := interpreters[ce.language]
+	if !exists {
+		ce.logger.Log(logger.ERROR, "Code execution failed: unsupported language %q", ce.language)
+		return Result{ExitCode: -1}, fmt.Errorf("unsupported code language: %q", ce.language)
+	}
+
+	workDir, err := os.MkdirTemp(ce.dir, "calderat-code-")
+	if err != nil {
+		return Result{ExitCode: -1}, fmt.Errorf("failed to create code directory: %v", err)
+	}
+	defer os.RemoveAll(workDir)
+	if workDir, err = filepath.Abs(workDir); err != nil {
+		return Result{ExitCode: -1}, fmt.Errorf("failed to resolve code directory: %v", err)
+	}
+
+	script := filepath.Join(workDir, "main"+interpreter.Extension)
+	if err := os.WriteFile(script, []byte(request.Command), 0700); err != nil {
+		return Result{ExitCode: -1}, fmt.Errorf("failed to write code file: %v", err)
+	}
+
+	deadline := time.Now().Add(request.Timeout)
+	code := process{dir: ce.dir}
+	if ce.language == "psh" || ce.language == "cmd" {
+		code.codePage = consoleCodePage()
+	}
+	if ce.language == "go" && ce.buildTarget != "" {
+		binary, result, err := ce.build(ctx, script, workDir, request)
+		if err != nil {
+			return report(ce.logger, ce.language, result, err)
+		}
+		code.path = binary
+	} else {
+		path, err := lookPath(interpreter.Paths)
+		if err != nil {
+			ce.logger.Log(logger.ERROR, "No interpreter found for %s: %v", ce.language, err)
+			return Result{ExitCode: -1}, fmt.Errorf("%w: no interpreter found for %s: %v", ErrExecutorUnavailable, ce.language, err)
+		}
+		code.path = path
+		code.args = append(append([]string{}, interpreter.Args...), script)
+	}
+
+	ce.logger.Log(logger.INFO, "Executing %s code by %s (Timeout: %v)", ce.language, code.path, request.Timeout)
+	ce.logger.Log(logger.DEBUG, "Code:\n%s", request.Command)
+
+	request.Timeout = time.Until(deadline)
+	result, err := code.run(ctx, request)
+	return report(ce.logger, ce.language, result, err)
+}
+
+// build compiles Go code into the build target with the local toolchain, within
+// the timeout of the request.
+func (ce *Code) build(ctx context.Context, script, workDir string, request Request) (string, Result, error) {
+	toolchain, err := exec.LookPath("go")
+	if err != nil {
+		ce.logger.Log(logger.ERROR, "No Go toolchain available to build %s", ce.buildTarget)
+		return "", Result{ExitCode: -1}, fmt.Errorf("%w: no Go toolchain available to build %s: %v", ErrExecutorUnavailable, ce.buildTarget, err)
+	}
+	binary := filepath.Join(workDir, filepath.Base(ce.buildTarget))
+	ce.logger.Log(logger.INFO, "Building %s with %s", filepath.Base(ce.buildTarget), toolchain)
+	build := process{
+		path: toolchain,
+		args: []string{"build", "-o", binary, script},
+		dir:  workDir,
+		env:  append(os.Environ(), "GO111MODULE=off"),
+	}
+	// The stdin, terminal and start of the request are meant for the built code
+	request.Stdin, request.TTY, request.Started = "", false, nil
+	result, err := build.run(ctx, request)
+	if err != nil {
+		return "", result, fmt.Errorf("failed to build %s: %w", ce.buildTarget, err)
+	}
+	return binary, result, nil
+}
+
+func lookPath(candidates []string) (string, error) {
+	var err error
+	for _, candidate := range candidates {
+		var path string
+		if path, err = exec.LookPath(candidate); err == nil {
+			return path, nil
+		}
+	}
+	return "", err
+}
+
+func (ce *Code) ShortName() string {
+	return ce.shortName
+}
+
+// SetWorkingDir sets the directory code runs from (empty means the current directory)
+func (ce *Code) SetWorkingDir(dir string) {
+	ce.dir = dir
+}
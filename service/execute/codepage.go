@@ -0,0 +1,64 @@
+package execute
+
+import "strings"
+
+// codePages maps the legacy code pages of Windows consoles to the characters of
+// their bytes 0x80 to 0xFF. The lower half is ASCII in all of them.
+var codePages = map[string][]rune{
+	CP437: []rune("ÇüéâäàåçêëèïîìÄÅ" +
+		"ÉæÆôöòûùÿÖÜ¢£¥₧ƒ" +
+		"áíóúñÑªº¿⌐¬½¼¡«»" +
+		"░▒▓│┤╡╢╖╕╣║╗╝╜╛┐" +
+		"└┴┬├─┼╞╟╚╔╩╦╠═╬╧" +
+		"╨╤╥╙╘╒╓╫╪┘┌█▄▌▐▀" +
+		"αßΓπΣσµτΦΘΩδ∞φε∩" +
+		"≡±≥≤⌠⌡÷≈°∙·√ⁿ²■\u00a0"),
+	CP850: []rune("ÇüéâäàåçêëèïîìÄÅ" +
+		"ÉæÆôöòûùÿÖÜø£Ø×ƒ" +
+		"áíóúñÑªº¿®¬½¼¡«»" +
+		"░▒▓│┤ÁÂÀ©╣║╗╝¢¥┐" +
+		"└┴┬├─┼ãÃ╚╔╩╦╠═╬¤" +
+		"ðÐÊËÈıÍÎÏ┘┌█▄¦Ì▀" +
+		"ÓßÔÒõÕµþÞÚÛÙýÝ¯´" +
+		"\u00ad±‗¾¶§÷¸°¨·¹³²■\u00a0"),
+	WINDOWS1252: windows1252(),
+}
+
+// windows1252 returns the upper half of Windows-1252: Latin-1 apart from the
+// punctuation in 0x80 to 0x9F. Unassigned bytes decode to U+FFFD.
+func windows1252() []rune {
+	table := []rune("€�‚ƒ„…†‡ˆ‰Š‹Œ�Ž�" +
+		"�‘’“”•–—˜™š›œ�žŸ")
+	for b := 0xA0; b <= 0xFF; b++ {
+		table = append(table, rune(b))
+	}
+	return table
+}
+
+// decodeCodePage converts bytes of a single-byte code page to a UTF-8 string.
+func decodeCodePage(b []byte, table []rune) string {
+	var text strings.Builder
+	text.Grow(len(b))
+	for _, c := range b {
+		if c < 0x80 {
+			text.WriteByte(c)
+		} else {
+			text.WriteRune(table[c-0x80])
+		}
+	}
+	return text.String()
+}
+
+// codePageName returns the name of a Windows code page identifier, or an empty
+// string when its output is UTF-8 or the code page is not supported.
+func codePageName(id uint32) string {
+	switch id {
+	case 437:
+		return CP437
+	case 850:
+		return CP850
+	case 1252:
+		return WINDOWS1252
+	}
+	return ""
+}
@@ -1,8 +1,71 @@
 package execute
 
-import "time"
+import (
+	"context"
+	"errors"
+	"io/fs"
+	"os/exec"
+	"time"
+)
 
+// ErrExecutorUnavailable marks failures where a command never ran because the
+// executor cannot be used on this host (unsupported OS, missing interpreter).
+var ErrExecutorUnavailable = errors.New("executor unavailable")
+
+// ErrTimeout marks commands killed because they ran longer than their timeout.
+var ErrTimeout = errors.New("command timed out")
+
+// ErrInterrupted marks commands killed because their context was cancelled,
+// e.g. when the operation is stopped by a signal.
+var ErrInterrupted = errors.New("execution interrupted")
+
+// Request is a command for an executing service to run.
+type Request struct {
+	Command string
+	Timeout time.Duration
+	// Stdin is written to the command, which otherwise reads from the null device
+	Stdin string
+	// TTY runs the command on a pseudo-terminal: stdin is typed into it and
+	// stdout and stderr are both read from it as stdout.
+	TTY bool
+	// Lines, when set, receives the output line by line while the command runs.
+	// It is not closed by the executing service.
+	Lines chan<- Line
+	// OutputLimit is the number of bytes kept of each output stream, the first and
+	// last halves of it when the command writes more. Zero means DefaultOutputLimit.
+	OutputLimit int
+	// Encoding forces the encoding output is decoded from instead of detecting it.
+	Encoding string
+	// Started, when set, is called with the process id once the command started.
+	Started func(pid int)
+}
+
+// Result describes how a command ended. Output is kept when the command fails.
+// ExitCode is -1 when the process never started or was ended by a signal.
+// The captures tell whether the output was truncated and how it was decoded;
+// binary output is base64-encoded.
+type Result struct {
+	Stdout        string
+	Stderr        string
+	StdoutCapture Capture
+	StderrCapture Capture
+	ExitCode      int
+	Signal        string
+	Pid           int
+}
+
+// ExecutingService runs commands. Cancelling the context kills the command and
+// every process it started.
 type ExecutingService interface {
-	Execute(string, time.Duration) (string, error)
+	Execute(context.Context, Request) (Result, error)
 	ShortName() string
+	SetWorkingDir(string)
+}
+
+// isStartFailure reports whether a command failed before it started, e.g. because
+// its executable does not exist or cannot be run.
+func isStartFailure(err error) bool {
+	var execErr *exec.Error
+	var pathErr *fs.PathError
+	return errors.As(err, &execErr) || errors.As(err, &pathErr)
 }
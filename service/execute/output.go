@@ -0,0 +1,217 @@
+package execute
+
+import (
+	"bytes"
+	"encoding/base64"
+	"fmt"
+	"unicode/utf16"
+	"unicode/utf8"
+)
+
+// DefaultOutputLimit is the number of bytes kept of each output stream when a
+// request does not set its own limit.
+const DefaultOutputLimit = 1 << 20
+
+// Encodings output can be decoded from. BASE64 marks binary output.
+const (
+	UTF8        = "utf-8"
+	UTF16LE     = "utf-16le"
+	UTF16BE     = "utf-16be"
+	CP437       = "cp437"
+	CP850       = "cp850"
+	WINDOWS1252 = "windows-1252"
+	BASE64      = "base64"
+)
+
+// Capture describes how an output stream of a command was kept: its size, the
+// bytes dropped when it went over the limit and the encoding it was read as.
+type Capture struct {
+	Bytes     int64  `json:"bytes"`               // Bytes written by the command
+	Truncated bool   `json:"truncated,omitempty"` // Only the head and tail were kept
+	Omitted   int64  `json:"omitted,omitempty"`   // Bytes dropped between head and tail
+	Encoding  string `json:"encoding,omitempty"`  // Encoding of the raw bytes, base64 for binary output
+	Binary    bool   `json:"binary,omitempty"`    // The output is not text and is stored base64-encoded
+	File      string `json:"file,omitempty"`      // Artifact file binary output was spilled to
+}
+
+// capture is a writer keeping the first and last bytes of a stream within a
+// limit: the first half is kept as written, the second half in a ring holding
+// the most recent bytes.
+type capture struct {
+	limit int
+	head  []byte
+	tail  []byte
+	next  int // Oldest byte of the tail once the ring is full
+	total int64
+}
+
+func newCapture(limit int) *capture {
+	if limit <= 0 {
+		limit = DefaultOutputLimit
+	}
+	return &capture{limit: limit}
+}
+
+func (c *capture) Write(p []byte) (int, error) {
+	n := len(p)
+	c.total += int64(n)
+	if room := c.limit - c.limit/2 - len(c.head); room > 0 {
+		take := min(room, len(p))
+		c.head = append(c.head, p[:take]...)
+		p = p[take:]
+	}
+	size := c.limit / 2
+	if size == 0 || len(p) == 0 {
+		return n, nil
+	}
+	if len(p) >= size {
+		c.tail = append(c.tail[:0], p[len(p)-size:]...)
+		c.next = 0
+		return n, nil
+	}
+	for len(p) > 0 {
+		if len(c.tail) < size {
+			take := min(size-len(c.tail), len(p))
+			c.tail = append(c.tail, p[:take]...)
+			p = p[take:]
+			continue
+		}
+		copied := copy(c.tail[c.next:], p)
+		p = p[copied:]
+		c.next = (c.next + copied) % size
+	}
+	return n, nil
+}
+
+// omitted returns the number of bytes dropped between the head and the tail.
+func (c *capture) omitted() int64 {
+	return c.total - int64(len(c.head)) - int64(len(c.tail))
+}
+
+// output decodes the kept bytes. Text is returned with a marker where bytes were
+// dropped; binary output is returned base64-encoded. The encoding overrides the
+// detection when set, codePage is the legacy code page non UTF-8 text falls back to.
+func (c *capture) output(encoding, codePage string) (string, Capture) {
+	tail := append(append([]byte{}, c.tail[c.next:]...), c.tail[:c.next]...)
+	info := Capture{Bytes: c.total, Omitted: c.omitted()}
+	info.Truncated = info.Omitted > 0
+	if c.total == 0 {
+		return "", info
+	}
+	if encoding == "" {
+		encoding = detectEncoding(c.head, info.Truncated, codePage)
+	}
+	info.Encoding = encoding
+	if encoding == BASE64 {
+		info.Binary = true
+		return base64.StdEncoding.EncodeToString(append(c.head, tail...)), info
+	}
+	if !info.Truncated {
+		return decode(append(c.head, tail...), encoding), info
+	}
+	// The tail starts where the omitted bytes end, possibly inside a character
+	if encoding == UTF16LE || encoding == UTF16BE {
+		if (int64(len(c.head))+info.Omitted)%2 == 1 && len(tail) > 0 {
+			tail = tail[1:]
+		}
+	} else if encoding == UTF8 {
+		for i := 0; i < utf8.UTFMax && len(tail) > 0 && !utf8.RuneStart(tail[0]); i++ {
+			tail = tail[1:]
+		}
+	}
+	return decode(c.head, encoding) + fmt.Sprintf("\n[... %d bytes omitted ...]\n", info.Omitted) + decode(tail, encoding), info
+}
+
+// detectEncoding guesses the encoding of output from its first bytes: a byte
+// order mark, the NUL bytes of UTF-16 text, valid UTF-8 or the legacy code page.
+// Anything else is binary.
+func detectEncoding(head []byte, truncated bool, codePage string) string {
+	switch {
+	case bytes.HasPrefix(head, []byte{0xEF, 0xBB, 0xBF}):
+		return UTF8
+	case bytes.HasPrefix(head, []byte{0xFF, 0xFE}):
+		return UTF16LE
+	case bytes.HasPrefix(head, []byte{0xFE, 0xFF}):
+		return UTF16BE
+	}
+	if encoding := detectUTF16(head); encoding != "" {
+		return encoding
+	}
+	if bytes.IndexByte(head, 0) >= 0 {
+		return BASE64
+	}
+	if validUTF8(head, truncated) {
+		return UTF8
+	}
+	if _, known := codePages[codePage]; known {
+		return codePage
+	}
+	return BASE64
+}
+
+// detectUTF16 recognizes UTF-16 text without a byte order mark, such as the
+// output of cmd /U, from mostly ASCII characters whose high byte is NUL.
+func detectUTF16(head []byte) string {
+	if len(head) < 4 {
+		return ""
+	}
+	pairs := len(head) / 2
+	var evenNul, oddNul int
+	for i := 0; i+1 < len(head); i += 2 {
+		if head[i] == 0 {
+			evenNul++
+		}
+		if head[i+1] == 0 {
+			oddNul++
+		}
+	}
+	switch {
+	case oddNul*10 >= pairs*9 && evenNul*10 < pairs:
+		return UTF16LE
+	case evenNul*10 >= pairs*9 && oddNul*10 < pairs:
+		return UTF16BE
+	}
+	return ""
+}
+
+// validUTF8 reports whether b is UTF-8, allowing a character cut at the end of
+// truncated output.
+func validUTF8(b []byte, truncated bool) bool {
+	if utf8.Valid(b) {
+		return true
+	}
+	if !truncated {
+		return false
+	}
+	for i := 1; i < utf8.UTFMax && i <= len(b); i++ {
+		if utf8.RuneStart(b[len(b)-i]) {
+			return utf8.Valid(b[:len(b)-i])
+		}
+	}
+	return false
+}
+
+// decode converts bytes in a text encoding to a UTF-8 string.
+func decode(b []byte, encoding string) string {
+	switch encoding {
+	case UTF8:
+		return string(bytes.TrimPrefix(b, []byte{0xEF, 0xBB, 0xBF}))
+	case UTF16LE, UTF16BE:
+		units := make([]uint16, 0, len(b)/2)
+		for i := 0; i+1 < len(b); i += 2 {
+			if encoding == UTF16LE {
+				units = append(units, uint16(b[i])|uint16(b[i+1])<<8)
+			} else {
+				units = append(units, uint16(b[i])<<8|uint16(b[i+1]))
+			}
+		}
+		if len(units) > 0 && units[0] == 0xFEFF {
+			units = units[1:]
+		}
+		return string(utf16.Decode(units))
+	}
+	if table, known := codePages[encoding]; known {
+		return decodeCodePage(b, table)
+	}
+	return string(b)
+}
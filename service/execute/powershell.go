@@ -1,14 +1,11 @@
 package execute
 
 import (
-	"calderat/utils/colorprint"
 	"calderat/utils/logger"
 	"context"
 	"fmt"
-	"os/exec"
 	"runtime"
 	"strings"
-	"time"
 )
 
 type PowerShell struct {
@@ -16,6 +13,7 @@ type PowerShell struct {
 	path      string   // Path to the PowerShell executable
 	execArgs  []string // Default arguments for the shell execution
 	logger    *logger.Logger
+	dir       string // Working directory commands run from
 }
 
 // NewPowerShell initializes a new PowerShell instance
@@ -28,42 +26,22 @@ func NewPowerShell(log *logger.Logger) *PowerShell {
 	}
 }
 
-// Execute runs a PowerShell command with a specified timeout and logs the process
-func (ps *PowerShell) Execute(command string, timeout time.Duration) (string, error) {
+// Execute runs a PowerShell command with the request timeout and logs the process
+func (ps *PowerShell) Execute(ctx context.Context, request Request) (Result, error) {
 	if runtime.GOOS != "windows" {
 		ps.logger.Log(logger.ERROR, "Command execution failed: Unsupported OS")
-		return "", fmt.Errorf("%s is only supported on Windows systems", ps.shortName)
+		return Result{ExitCode: -1}, fmt.Errorf("%w: %s is only supported on Windows systems", ErrExecutorUnavailable, ps.shortName)
 	}
 
-	ps.logger.Log(logger.INFO, "Executing command: %s by %s (Timeout: %v)", command, ps.shortName, timeout)
+	ps.logger.Log(logger.INFO, "Executing command: %s by %s (Timeout: %v)", request.Command, ps.shortName, request.Timeout)
 	ps.logger.Log(logger.TRACE, "Full arguments: %v", ps.execArgs)
 
-	// Create a context with the specified timeout
-	ctx, cancel := context.WithTimeout(context.Background(), timeout)
-	defer cancel()
-
 	// Append the command to the default execution arguments
-	args := append(ps.execArgs, command)
-
-	// Construct the execution command
-	cmd := exec.CommandContext(ctx, ps.path, args...)
-
-	// Capture the output
-	output, err := cmd.CombinedOutput()
-
-	// Check for context timeout
-	if ctx.Err() == context.DeadlineExceeded {
-		ps.logger.Log(logger.ERROR, "Command timed out after %v", timeout)
-		return "", fmt.Errorf("command timed out after %v", timeout)
-	}
+	args := append(append([]string{}, ps.execArgs...), request.Command)
 
-	if err != nil {
-		fmt.Println(colorprint.ColorString(fmt.Sprintf("Command execution failed: %v\nOutput: %s", err, string(output)), colorprint.RED))
-		return "", fmt.Errorf("failed to execute %s command: %v\nOutput: %s", ps.shortName, err, string(output))
-	}
-
-	ps.logger.Log(logger.DEBUG, "Command executed successfully. Output:\n%s", strings.TrimRight(string(output), " \n\r"))
-	return strings.TrimRight(string(output), " \n\r"), nil
+	result, err := process{path: ps.path, args: args, dir: ps.dir, codePage: consoleCodePage()}.run(ctx, request)
+	result.Stdout = strings.TrimRight(result.Stdout, " \n\r")
+	return report(ps.logger, ps.shortName, result, err)
 }
 
 func (p *PowerShell) ShortName() string {
@@ -73,3 +51,8 @@ func (p *PowerShell) ShortName() string {
 func (p *PowerShell) Path() string {
 	return p.path
 }
+
+// SetWorkingDir sets the directory commands run from (empty means the current directory)
+func (ps *PowerShell) SetWorkingDir(dir string) {
+	ps.dir = dir
+}
@@ -0,0 +1,110 @@
+package execute
+
+import (
+	"calderat/utils/colorprint"
+	"calderat/utils/logger"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os/exec"
+	"strings"
+)
+
+// process describes a command line to start for an executor.
+type process struct {
+	path string
+	args []string
+	dir  string
+	env  []string
+	// codePage is the legacy code page output that is not UTF-8 is decoded from
+	codePage string
+}
+
+// run starts the process in its own process group with the timeout of the request
+// and waits for it. Timeouts and cancellation kill the whole group. Errors wrap
+// ErrTimeout, ErrInterrupted or ErrExecutorUnavailable when they apply. Each
+// output stream is kept within the output limit of the request and decoded.
+func (p process) run(ctx context.Context, request Request) (Result, error) {
+	timeout := request.Timeout
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	cmd := exec.CommandContext(ctx, p.path, p.args...)
+	cmd.Dir = p.dir
+	if p.env != nil {
+		cmd.Env = p.env
+	}
+	killProcessGroupOnCancel(cmd)
+
+	stdout, stderr := newCapture(request.OutputLimit), newCapture(request.OutputLimit)
+	var stdoutWriter, stderrWriter io.Writer = stdout, stderr
+	if request.Lines != nil {
+		stdoutLines, stderrLines := newLineWriter(ctx, STDOUT, request.Lines), newLineWriter(ctx, STDERR, request.Lines)
+		defer stdoutLines.Flush()
+		defer stderrLines.Flush()
+		stdoutWriter = io.MultiWriter(stdout, stdoutLines)
+		stderrWriter = io.MultiWriter(stderr, stderrLines)
+	}
+	var err error
+	if request.TTY {
+		err = runOnTerminal(cmd, request, stdoutWriter)
+	} else {
+		cmd.Stdout, cmd.Stderr = stdoutWriter, stderrWriter
+		if request.Stdin != "" {
+			cmd.Stdin = strings.NewReader(request.Stdin)
+		}
+		if err = cmd.Start(); err == nil {
+			started(cmd, request)
+			err = cmd.Wait()
+		}
+	}
+	result := Result{ExitCode: -1}
+	result.Stdout, result.StdoutCapture = stdout.output(request.Encoding, p.codePage)
+	result.Stderr, result.StderrCapture = stderr.output(request.Encoding, p.codePage)
+	if cmd.Process != nil {
+		result.Pid = cmd.Process.Pid
+	}
+	if cmd.ProcessState != nil {
+		result.ExitCode = cmd.ProcessState.ExitCode()
+		result.Signal = terminatingSignal(cmd.ProcessState)
+	}
+
+	switch {
+	case ctx.Err() == context.DeadlineExceeded:
+		return result, fmt.Errorf("%w after %v", ErrTimeout, timeout)
+	case ctx.Err() == context.Canceled:
+		return result, ErrInterrupted
+	case err != nil && isStartFailure(err):
+		return result, fmt.Errorf("%w: %v", ErrExecutorUnavailable, err)
+	}
+	return result, err
+}
+
+// started tells the request the command started.
+func started(cmd *exec.Cmd, request Request) {
+	if request.Started != nil {
+		request.Started(cmd.Process.Pid)
+	}
+}
+
+// report logs how a command ended and returns the error callers should see,
+// named after the executor.
+func report(log *logger.Logger, name string, result Result, err error) (Result, error) {
+	switch {
+	case err == nil:
+		log.Log(logger.DEBUG, "Command executed successfully (pid %d). Output:\n%s", result.Pid, result.Stdout)
+		return result, nil
+	case errors.Is(err, ErrTimeout):
+		log.Log(logger.WARN, "Command timed out, process group %d killed", result.Pid)
+		return result, err
+	case errors.Is(err, ErrInterrupted):
+		log.Log(logger.WARN, "Command interrupted, process group %d killed", result.Pid)
+		return result, fmt.Errorf("%w: %s", ErrInterrupted, name)
+	case errors.Is(err, ErrExecutorUnavailable):
+		log.Log(logger.ERROR, "Could not start %s: %v", name, err)
+		return result, fmt.Errorf("%w: %s", err, name)
+	}
+	fmt.Println(colorprint.ColorString(fmt.Sprintf("Command execution failed: %v\nOutput: %s%s", err, result.Stdout, result.Stderr), colorprint.RED))
+	return result, fmt.Errorf("failed to execute %s command: %v", name, err)
+}
@@ -0,0 +1,35 @@
+//go:build !windows
+
+package execute
+
+import (
+	"os"
+	"os/exec"
+	"syscall"
+	"time"
+)
+
+// killProcessGroupOnCancel starts the command in its own session, hence its own
+// process group, and kills the whole group when the command context is done, so
+// children spawned by the shell do not outlive it.
+func killProcessGroupOnCancel(cmd *exec.Cmd) {
+	cmd.SysProcAttr = &syscall.SysProcAttr{Setsid: true}
+	cmd.Cancel = func() error {
+		return syscall.Kill(-cmd.Process.Pid, syscall.SIGKILL)
+	}
+	// Do not wait forever on output pipes held open by descendants that left the group
+	cmd.WaitDelay = 2 * time.Second
+}
+
+// terminatingSignal returns the name of the signal that ended the process, if any.
+func terminatingSignal(state *os.ProcessState) string {
+	if status, ok := state.Sys().(syscall.WaitStatus); ok && status.Signaled() {
+		return status.Signal().String()
+	}
+	return ""
+}
+
+// consoleCodePage returns an empty string, output on these systems is UTF-8.
+func consoleCodePage() string {
+	return ""
+}
@@ -0,0 +1,41 @@
+//go:build windows
+
+package execute
+
+import (
+	"os"
+	"os/exec"
+	"strconv"
+	"syscall"
+	"time"
+)
+
+// killProcessGroupOnCancel starts the command in a new process group and kills
+// its process tree when the command context is done, so children spawned by the
+// shell do not outlive it.
+func killProcessGroupOnCancel(cmd *exec.Cmd) {
+	cmd.SysProcAttr = &syscall.SysProcAttr{CreationFlags: syscall.CREATE_NEW_PROCESS_GROUP}
+	cmd.Cancel = func() error {
+		kill := exec.Command("taskkill", "/T", "/F", "/PID", strconv.Itoa(cmd.Process.Pid))
+		if err := kill.Run(); err != nil {
+			return cmd.Process.Kill()
+		}
+		return nil
+	}
+	// Do not wait forever on output pipes held open by descendants that left the tree
+	cmd.WaitDelay = 2 * time.Second
+}
+
+// terminatingSignal returns an empty string, Windows processes are not ended by signals.
+func terminatingSignal(state *os.ProcessState) string {
+	return ""
+}
+
+var getOEMCP = syscall.NewLazyDLL("kernel32.dll").NewProc("GetOEMCP")
+
+// consoleCodePage returns the OEM code page cmd and PowerShell write redirected
+// output in, or an empty string when it is not supported.
+func consoleCodePage() string {
+	id, _, _ := getOEMCP.Call()
+	return codePageName(uint32(id))
+}
@@ -0,0 +1,56 @@
+package execute
+
+import (
+	"fmt"
+	"io"
+	"os/exec"
+	"strings"
+	"time"
+)
+
+// eot is typed after the stdin of a command on a terminal to end its input.
+const eot = "\x04"
+
+// runOnTerminal runs the command with a pseudo-terminal as its controlling
+// terminal. The stdin of the request is typed into the terminal, without echo,
+// and everything the command writes to the terminal goes to output.
+func runOnTerminal(cmd *exec.Cmd, request Request, output io.Writer) error {
+	terminal, tty, err := openPTY()
+	if err != nil {
+		return fmt.Errorf("%w: no pseudo-terminal: %v", ErrExecutorUnavailable, err)
+	}
+	defer terminal.Close()
+	cmd.Stdin, cmd.Stdout, cmd.Stderr = tty, tty, tty
+	setControllingTerminal(cmd)
+	err = cmd.Start()
+	tty.Close()
+	if err != nil {
+		return err
+	}
+	started(cmd, request)
+
+	copied := make(chan struct{})
+	go func() {
+		defer close(copied)
+		// Reading fails once every process holding the terminal has exited
+		io.Copy(output, terminal)
+	}()
+	go func() {
+		stdin := request.Stdin
+		if stdin != "" && !strings.HasSuffix(stdin, "\n") {
+			// The first end of transmission only ends an unfinished line
+			stdin += eot
+		}
+		io.WriteString(terminal, stdin+eot)
+	}()
+
+	err = cmd.Wait()
+	select {
+	case <-copied:
+	case <-time.After(cmd.WaitDelay):
+		// Descendants that left the process group still hold the terminal
+		terminal.Close()
+		<-copied
+	}
+	return err
+}
@@ -0,0 +1,58 @@
+//go:build linux
+
+package execute
+
+import (
+	"fmt"
+	"os"
+	"os/exec"
+	"syscall"
+	"unsafe"
+)
+
+// openPTY opens a new pseudo-terminal from /dev/ptmx and returns its master side
+// and its terminal. Echo is turned off so stdin does not show in the output, and
+// newlines are not translated. The master is non-blocking so closing it ends reads.
+func openPTY() (*os.File, *os.File, error) {
+	fd, err := syscall.Open("/dev/ptmx", syscall.O_RDWR|syscall.O_NOCTTY|syscall.O_CLOEXEC|syscall.O_NONBLOCK, 0)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to open /dev/ptmx: %w", err)
+	}
+	var unlock int32
+	if err := ioctl(uintptr(fd), syscall.TIOCSPTLCK, unsafe.Pointer(&unlock)); err != nil {
+		syscall.Close(fd)
+		return nil, nil, fmt.Errorf("failed to unlock /dev/ptmx: %w", err)
+	}
+	var number uint32
+	if err := ioctl(uintptr(fd), syscall.TIOCGPTN, unsafe.Pointer(&number)); err != nil {
+		syscall.Close(fd)
+		return nil, nil, fmt.Errorf("failed to get terminal number: %w", err)
+	}
+	master := os.NewFile(uintptr(fd), "/dev/ptmx")
+	tty, err := os.OpenFile(fmt.Sprintf("/dev/pts/%d", number), os.O_RDWR|syscall.O_NOCTTY, 0)
+	if err != nil {
+		master.Close()
+		return nil, nil, err
+	}
+	var termios syscall.Termios
+	if err := ioctl(tty.Fd(), syscall.TCGETS, unsafe.Pointer(&termios)); err == nil {
+		termios.Lflag &^= syscall.ECHO
+		termios.Oflag &^= syscall.ONLCR
+		ioctl(tty.Fd(), syscall.TCSETS, unsafe.Pointer(&termios))
+	}
+	return master, tty, nil
+}
+
+// setControllingTerminal makes the terminal on the stdin of the command its
+// controlling terminal, in the session the command starts.
+func setControllingTerminal(cmd *exec.Cmd) {
+	cmd.SysProcAttr.Setctty = true
+	cmd.SysProcAttr.Ctty = 0
+}
+
+func ioctl(fd uintptr, request uintptr, arg unsafe.Pointer) error {
+	if _, _, errno := syscall.Syscall(syscall.SYS_IOCTL, fd, request, uintptr(arg)); errno != 0 {
+		return errno
+	}
+	return nil
+}
@@ -0,0 +1,16 @@
+//go:build !linux
+
+package execute
+
+import (
+	"errors"
+	"os"
+	"os/exec"
+)
+
+// openPTY fails, pseudo-terminals are only supported on Linux.
+func openPTY() (*os.File, *os.File, error) {
+	return nil, nil, errors.New("terminal mode is only supported on Linux")
+}
+
+func setControllingTerminal(cmd *exec.Cmd) {}
@@ -1,19 +1,17 @@
 package execute
 
 import (
-	"calderat/utils/colorprint"
 	"calderat/utils/logger"
 	"context"
 	"fmt"
-	"os/exec"
 	"runtime"
-	"time"
 )
 
 type Sh struct {
 	shortName string
 	logger    *logger.Logger
 	path      string
+	dir       string
 }
 
 // NewSh initializes a new SH executor
@@ -25,38 +23,17 @@ func NewSh(log *logger.Logger) *Sh {
 	}
 }
 
-// Execute runs an SH command with a specified timeout
-func (se *Sh) Execute(command string, timeout time.Duration) (string, error) {
+// Execute runs an SH command with the request timeout
+func (se *Sh) Execute(ctx context.Context, request Request) (Result, error) {
 	if runtime.GOOS != "linux" {
 		se.logger.Log(logger.ERROR, "Command execution failed: Unsupported OS")
-		return "", fmt.Errorf("%s is only supported on Linux systems", se.shortName)
+		return Result{ExitCode: -1}, fmt.Errorf("%w: %s is only supported on Linux systems", ErrExecutorUnavailable, se.shortName)
 	}
 
-	se.logger.Log(logger.INFO, "Executing command: %s by %s (Timeout: %v)", command, se.shortName, timeout)
+	se.logger.Log(logger.INFO, "Executing command: %s by %s (Timeout: %v)", request.Command, se.shortName, request.Timeout)
 
-	// Create a context with the specified timeout
-	ctx, cancel := context.WithTimeout(context.Background(), timeout)
-	defer cancel()
-
-	// Construct the execution command
-	cmd := exec.CommandContext(ctx, se.path, "-c", command)
-
-	// Capture the output
-	output, err := cmd.CombinedOutput()
-
-	// Check for context timeout
-	if ctx.Err() == context.DeadlineExceeded {
-		se.logger.Log(logger.WARN, "Command timed out after %v", timeout)
-		return "", fmt.Errorf("command timed out after %v", timeout)
-	}
-
-	if err != nil {
-		fmt.Println(colorprint.ColorString(fmt.Sprintf("Command execution failed: %v\nOutput: %s", err, string(output)), colorprint.RED))
-		return "", fmt.Errorf("failed to execute %s command: %v\nOutput: %s", se.shortName, err, string(output))
-	}
-
-	se.logger.Log(logger.DEBUG, "Command executed successfully. Output:\n%s", string(output))
-	return string(output), nil
+	result, err := process{path: se.path, args: []string{"-c", request.Command}, dir: se.dir}.run(ctx, request)
+	return report(se.logger, se.shortName, result, err)
 }
 
 func (se *Sh) ShortName() string {
@@ -66,3 +43,8 @@ func (se *Sh) ShortName() string {
 func (se *Sh) Path() string {
 	return se.path
 }
+
+// SetWorkingDir sets the directory commands run from (empty means the current directory)
+func (se *Sh) SetWorkingDir(dir string) {
+	se.dir = dir
+}
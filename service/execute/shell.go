@@ -0,0 +1,81 @@
+package execute
+
+import (
+	"calderat/utils/logger"
+	"context"
+	"os/exec"
+)
+
+// Shell runs commands with an interpreter that takes the command as an argument,
+// such as bash -c, zsh -c, python3 -c, perl -e or pwsh -Command.
+type Shell struct {
+	shortName string   // Executor name used in abilities
+	path      string   // Path to the interpreter executable
+	execArgs  []string // Arguments placed before the command
+	logger    *logger.Logger
+	dir       string // Working directory commands run from
+}
+
+// NewShell initializes a new interpreter executor
+func NewShell(shortName, path string, execArgs []string, log *logger.Logger) *Shell {
+	return &Shell{
+		shortName: shortName,
+		path:      path,
+		execArgs:  execArgs,
+		logger:    log,
+	}
+}
+
+// NewBash initializes a new Bash executor
+func NewBash(log *logger.Logger) *Shell {
+	return NewShell("bash", "bash", []string{"-c"}, log)
+}
+
+// NewZsh initializes a new Zsh executor
+func NewZsh(log *logger.Logger) *Shell {
+	return NewShell("zsh", "zsh", []string{"-c"}, log)
+}
+
+// NewPython initializes a new Python executor, preferring python3 over python
+func NewPython(shortName string, log *logger.Logger) *Shell {
+	path := "python3"
+	if _, err := exec.LookPath(path); err != nil {
+		path = "python"
+	}
+	return NewShell(shortName, path, []string{"-c"}, log)
+}
+
+// NewPerl initializes a new Perl executor
+func NewPerl(log *logger.Logger) *Shell {
+	return NewShell("perl", "perl", []string{"-e"}, log)
+}
+
+// NewPwsh initializes a new PowerShell Core executor
+func NewPwsh(log *logger.Logger) *Shell {
+	return NewShell("pwsh", "/usr/bin/pwsh", []string{"-NoProfile", "-NonInteractive", "-Command"}, log)
+}
+
+// Execute runs a command with the interpreter and the request timeout
+func (sh *Shell) Execute(ctx context.Context, request Request) (Result, error) {
+	sh.logger.Log(logger.INFO, "Executing command: %s by %s (Timeout: %v)", request.Command, sh.shortName, request.Timeout)
+	sh.logger.Log(logger.TRACE, "Full arguments: %v", sh.execArgs)
+
+	// Append the command to the default execution arguments
+	args := append(append([]string{}, sh.execArgs...), request.Command)
+
+	result, err := process{path: sh.path, args: args, dir: sh.dir}.run(ctx, request)
+	return report(sh.logger, sh.shortName, result, err)
+}
+
+func (sh *Shell) ShortName() string {
+	return sh.shortName
+}
+
+func (sh *Shell) Path() string {
+	return sh.path
+}
+
+// SetWorkingDir sets the directory commands run from (empty means the current directory)
+func (sh *Shell) SetWorkingDir(dir string) {
+	sh.dir = dir
+}
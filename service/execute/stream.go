@@ -0,0 +1,78 @@
+package execute
+
+import (
+	"bytes"
+	"context"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	STDOUT = "STDOUT"
+	STDERR = "STDERR"
+)
+
+// maxLineLength is where long lines, e.g. of binary output, are split.
+const maxLineLength = 64 << 10
+
+// Line is one line of output written by a running command.
+type Line struct {
+	Stream string
+	Text   string
+	Time   time.Time
+}
+
+// lineWriter splits what a command writes into lines and sends them on a channel.
+// Sends give up once the context is done so a stalled reader cannot block the command.
+type lineWriter struct {
+	ctx     context.Context
+	stream  string
+	lines   chan<- Line
+	pending []byte
+	mutex   sync.Mutex
+}
+
+func newLineWriter(ctx context.Context, stream string, lines chan<- Line) *lineWriter {
+	return &lineWriter{ctx: ctx, stream: stream, lines: lines}
+}
+
+func (w *lineWriter) Write(p []byte) (int, error) {
+	w.mutex.Lock()
+	defer w.mutex.Unlock()
+	w.pending = append(w.pending, p...)
+	for {
+		end := bytes.IndexByte(w.pending, '\n')
+		if end < 0 {
+			break
+		}
+		w.send(string(w.pending[:end]))
+		w.pending = w.pending[end+1:]
+	}
+	if len(w.pending) >= maxLineLength {
+		w.send(string(w.pending))
+		w.pending = nil
+	}
+	return len(p), nil
+}
+
+// Flush sends the last line when the output does not end with a newline.
+func (w *lineWriter) Flush() {
+	w.mutex.Lock()
+	defer w.mutex.Unlock()
+	if len(w.pending) > 0 {
+		w.send(string(w.pending))
+		w.pending = nil
+	}
+}
+
+// send passes a line on without its carriage return, readable even when the
+// output is not UTF-8: NUL bytes, e.g. of UTF-16 output, are dropped and invalid
+// sequences replaced.
+func (w *lineWriter) send(text string) {
+	text = strings.TrimRight(strings.ToValidUTF8(strings.ReplaceAll(text, "\x00", ""), "\uFFFD"), "\r")
+	select {
+	case w.lines <- Line{Stream: w.stream, Text: text, Time: time.Now()}:
+	case <-w.ctx.Done():
+	}
+}
@@ -0,0 +1,252 @@
+package file
+
+import (
+	"calderat/utils/logger"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"gopkg.in/yaml.v2"
+)
+
+const (
+	ManifestFile = "manifest.yml"
+)
+
+// FileService moves files between the operation and the host: it stages the
+// payloads abilities need before their links run and collects the files they
+// name in their uploads afterwards.
+type FileService struct {
+	PayloadDir   string            // Local directory payloads are resolved from
+	PayloadURL   string            // Optional base URL payloads are downloaded from
+	WorkDir      string            // Directory payloads are staged in and links run from
+	ArtifactsDir string            // Directory uploads are collected into, per operation
+	UploadURL    string            // Optional endpoint uploads are POSTed to instead
+	Manifest     map[string]string // Payload name -> expected SHA-256
+	staged       map[string]bool   // Payloads written to the working directory by the service
+	mutex        sync.Mutex
+	logger       *logger.Logger
+	client       *http.Client
+}
+
+// NewFileService initializes a file service and loads the payload manifest
+// (payloadDir/manifest.yml) when one exists. The working directory is created
+// by CreateWorkDir once links are about to run.
+func NewFileService(payloadDir, payloadURL, workDir, artifactsDir, uploadURL string, log *logger.Logger) (*FileService, error) {
+	fs := &FileService{
+		PayloadDir:   payloadDir,
+		PayloadURL:   strings.TrimRight(payloadURL, "/"),
+		WorkDir:      workDir,
+		ArtifactsDir: artifactsDir,
+		UploadURL:    uploadURL,
+		Manifest:     map[string]string{},
+		staged:       map[string]bool{},
+		logger:       log,
+		client:       &http.Client{Timeout: 60 * time.Second},
+	}
+	if err := fs.loadManifest(); err != nil {
+		return nil, err
+	}
+	return fs, nil
+}
+
+// CreateWorkDir creates the working directory payloads are staged in and links run from.
+func (fs *FileService) CreateWorkDir() error {
+	if err := os.MkdirAll(fs.WorkDir, 0755); err != nil {
+		return fmt.Errorf("failed to create working directory %s: %w", fs.WorkDir, err)
+	}
+	return nil
+}
+
+func (fs *FileService) loadManifest() error {
+	rawData, err := os.ReadFile(filepath.Join(fs.PayloadDir, ManifestFile))
+	if errors.Is(err, os.ErrNotExist) {
+		fs.logger.Log(logger.TRACE, "No payload manifest found in %s", fs.PayloadDir)
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("error reading payload manifest: %w", err)
+	}
+	if err := yaml.Unmarshal(rawData, &fs.Manifest); err != nil {
+		return fmt.Errorf("error unmarshalling payload manifest: %w", err)
+	}
+	for name, sum := range fs.Manifest {
+		fs.Manifest[name] = strings.ToLower(sum)
+	}
+	fs.logger.Log(logger.TRACE, "Loaded %d payload hashes from manifest", len(fs.Manifest))
+	return nil
+}
+
+// StagePayloads copies (or downloads) every payload into the working directory
+// and verifies it against the manifest. Payloads already staged with the
+// expected hash are left in place.
+func (fs *FileService) StagePayloads(names []string) error {
+	for _, name := range names {
+		if err := fs.stagePayload(name); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (fs *FileService) stagePayload(name string) error {
+	if err := validName(name); err != nil {
+		return err
+	}
+	destination := filepath.Join(fs.WorkDir, name)
+	_, err := os.Stat(destination)
+	exists := err == nil
+	if exists && fs.Manifest[name] != "" && fs.verify(name, destination) == nil {
+		fs.logger.Log(logger.DEBUG, "Payload %s already staged", name)
+		return nil
+	}
+
+	source, err := fs.openPayload(name)
+	if err != nil {
+		return err
+	}
+	defer source.Close()
+
+	// The payload is written next to its destination and moved in place once verified
+	file, err := os.CreateTemp(fs.WorkDir, "."+name+".*")
+	if err != nil {
+		return fmt.Errorf("failed to stage payload %s: %w", name, err)
+	}
+	temporary := file.Name()
+	defer os.Remove(temporary)
+	_, err = io.Copy(file, source)
+	file.Close()
+	if err != nil {
+		return fmt.Errorf("failed to stage payload %s: %w", name, err)
+	}
+	if err := fs.verify(name, temporary); err != nil {
+		return err
+	}
+
+	if exists && !fs.isStaged(name) {
+		// A file of the operator is used as it is when it is the payload, never replaced
+		if !sameContent(temporary, destination) {
+			return fmt.Errorf("failed to stage payload %s: %s exists and was not staged by the operation", name, destination)
+		}
+		fs.logger.Log(logger.DEBUG, "Payload %s already in %s", name, fs.WorkDir)
+		return nil
+	}
+	if err := os.Chmod(temporary, 0755); err != nil {
+		return fmt.Errorf("failed to stage payload %s: %w", name, err)
+	}
+	if err := os.Rename(temporary, destination); err != nil {
+		return fmt.Errorf("failed to stage payload %s: %w", name, err)
+	}
+	fs.mutex.Lock()
+	fs.staged[name] = true
+	fs.mutex.Unlock()
+	fs.logger.Log(logger.INFO, "Staged payload %s in %s", name, fs.WorkDir)
+	return nil
+}
+
+func (fs *FileService) isStaged(name string) bool {
+	fs.mutex.Lock()
+	defer fs.mutex.Unlock()
+	return fs.staged[name]
+}
+
+// sameContent reports whether two files have the same SHA-256.
+func sameContent(path, other string) bool {
+	hash, _, err := HashFile(path)
+	if err != nil {
+		return false
+	}
+	otherHash, _, err := HashFile(other)
+	return err == nil && hash == otherHash
+}
+
+// openPayload opens a payload from the payload directory, falling back to the payload URL.
+func (fs *FileService) openPayload(name string) (io.ReadCloser, error) {
+	local, err := os.Open(filepath.Join(fs.PayloadDir, name))
+	if err == nil {
+		return local, nil
+	}
+	if fs.PayloadURL == "" {
+		return nil, fmt.Errorf("payload %s not found in %s: %w", name, fs.PayloadDir, err)
+	}
+
+	payloadURL := fs.PayloadURL + "/" + url.PathEscape(name)
+	fs.logger.Log(logger.DEBUG, "Downloading payload %s from %s", name, payloadURL)
+	response, err := fs.client.Get(payloadURL)
+	if err != nil {
+		return nil, fmt.Errorf("failed to download payload %s: %w", name, err)
+	}
+	if response.StatusCode != http.StatusOK {
+		response.Body.Close()
+		return nil, fmt.Errorf("failed to download payload %s: %s", name, response.Status)
+	}
+	return response.Body, nil
+}
+
+// verify compares the SHA-256 of a staged payload with the manifest entry.
+func (fs *FileService) verify(name, path string) error {
+	expected, exists := fs.Manifest[name]
+	if !exists {
+		fs.logger.Log(logger.WARN, "Payload %s has no manifest entry, skipping hash check", name)
+		return nil
+	}
+	actual, _, err := HashFile(path)
+	if err != nil {
+		return err
+	}
+	if actual != expected {
+		return fmt.Errorf("payload %s hash mismatch: expected %s, got %s", name, expected, actual)
+	}
+	return nil
+}
+
+// RemovePayloads deletes staged payloads from the working directory. Files the
+// service did not write, like a payload the operator placed there, are kept.
+func (fs *FileService) RemovePayloads(names []string) {
+	for _, name := range names {
+		if validName(name) != nil || !fs.isStaged(name) {
+			continue
+		}
+		path := filepath.Join(fs.WorkDir, name)
+		if err := os.Remove(path); err != nil && !errors.Is(err, os.ErrNotExist) {
+			fs.logger.Log(logger.WARN, "Failed to delete payload %s: %v", path, err)
+			continue
+		}
+		fs.mutex.Lock()
+		delete(fs.staged, name)
+		fs.mutex.Unlock()
+		fs.logger.Log(logger.DEBUG, "Deleted payload %s", path)
+	}
+}
+
+// HashFile returns the hex SHA-256 and the size of a file.
+func HashFile(path string) (string, int64, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return "", 0, fmt.Errorf("failed to open %s: %w", path, err)
+	}
+	defer file.Close()
+	hash := sha256.New()
+	size, err := io.Copy(hash, file)
+	if err != nil {
+		return "", 0, fmt.Errorf("failed to hash %s: %w", path, err)
+	}
+	return hex.EncodeToString(hash.Sum(nil)), size, nil
+}
+
+// validName rejects payload names that would escape the working directory.
+func validName(name string) error {
+	if name == "" || name != filepath.Base(name) || name == "." || name == ".." {
+		return fmt.Errorf("invalid payload name: %q", name)
+	}
+	return nil
+}
@@ -0,0 +1,125 @@
+package file
+
+import (
+	"bytes"
+	"calderat/secondclass"
+	"calderat/utils/logger"
+	"fmt"
+	"io"
+	"mime/multipart"
+	"net/http"
+	"os"
+	"path/filepath"
+)
+
+// UploadFiles collects the given files after a link finished. Files are copied
+// into the per-operation artifacts directory, or POSTed to the upload URL when
+// one is configured. Relative paths are resolved from the working directory.
+// Every file yields a record, including files that could not be collected.
+func (fs *FileService) UploadFiles(paths []string, operationId, linkId string) []secondclass.Upload {
+	uploads := []secondclass.Upload{}
+	for _, path := range paths {
+		if !filepath.IsAbs(path) {
+			path = filepath.Join(fs.WorkDir, path)
+		}
+		upload := secondclass.Upload{Path: path}
+		sum, size, err := HashFile(path)
+		if err != nil {
+			upload.Error = err.Error()
+			fs.logger.Log(logger.WARN, "Failed to collect %s: %v", path, err)
+			uploads = append(uploads, upload)
+			continue
+		}
+		upload.Sha256, upload.Size = sum, size
+
+		if fs.UploadURL != "" {
+			upload.Destination, err = fs.postFile(path, operationId, linkId)
+		} else {
+			upload.Destination, err = fs.copyToArtifacts(path, operationId, linkId)
+		}
+		if err != nil {
+			upload.Error = err.Error()
+			fs.logger.Log(logger.WARN, "Failed to collect %s: %v", path, err)
+		} else {
+			fs.logger.Log(logger.INFO, "Collected %s (%d bytes, sha256 %s) to %s", path, size, sum, upload.Destination)
+		}
+		uploads = append(uploads, upload)
+	}
+	return uploads
+}
+
+func (fs *FileService) copyToArtifacts(path, operationId, linkId string) (string, error) {
+	directory := filepath.Join(fs.ArtifactsDir, operationId, linkId)
+	if err := os.MkdirAll(directory, 0700); err != nil {
+		return "", fmt.Errorf("failed to create artifacts directory: %w", err)
+	}
+	source, err := os.Open(path)
+	if err != nil {
+		return "", err
+	}
+	defer source.Close()
+
+	destination := filepath.Join(directory, filepath.Base(path))
+	file, err := os.OpenFile(destination, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, 0600)
+	if err != nil {
+		return "", err
+	}
+	defer file.Close()
+	if _, err := io.Copy(file, source); err != nil {
+		return "", err
+	}
+	return destination, nil
+}
+
+// SaveArtifact writes data produced by a link, e.g. its binary output, to the
+// per-operation artifacts directory and returns the path of the file.
+func (fs *FileService) SaveArtifact(data []byte, name, operationId, linkId string) (string, error) {
+	directory := filepath.Join(fs.ArtifactsDir, operationId, linkId)
+	if err := os.MkdirAll(directory, 0700); err != nil {
+		return "", fmt.Errorf("failed to create artifacts directory: %w", err)
+	}
+	destination := filepath.Join(directory, name)
+	if err := os.WriteFile(destination, data, 0600); err != nil {
+		return "", err
+	}
+	return destination, nil
+}
+
+// postFile sends a file as multipart/form-data (field "file") to the upload URL.
+func (fs *FileService) postFile(path, operationId, linkId string) (string, error) {
+	source, err := os.Open(path)
+	if err != nil {
+		return "", err
+	}
+	defer source.Close()
+
+	body := &bytes.Buffer{}
+	writer := multipart.NewWriter(body)
+	part, err := writer.CreateFormFile("file", filepath.Base(path))
+	if err != nil {
+		return "", err
+	}
+	if _, err := io.Copy(part, source); err != nil {
+		return "", err
+	}
+	if err := writer.Close(); err != nil {
+		return "", err
+	}
+
+	request, err := http.NewRequest(http.MethodPost, fs.UploadURL, body)
+	if err != nil {
+		return "", err
+	}
+	request.Header.Set("Content-Type", writer.FormDataContentType())
+	request.Header.Set("X-Operation-Id", operationId)
+	request.Header.Set("X-Link-Id", linkId)
+	response, err := fs.client.Do(request)
+	if err != nil {
+		return "", err
+	}
+	defer response.Body.Close()
+	if response.StatusCode < 200 || response.StatusCode >= 300 {
+		return "", fmt.Errorf("upload rejected: %s", response.Status)
+	}
+	return fs.UploadURL, nil
+}
@@ -3,19 +3,209 @@ package knowledge
 import (
 	"calderat/secondclass"
 	"calderat/utils/logger"
+	"encoding/json"
+	"os"
 	"regexp"
+	"slices"
+	"strings"
+	"sync"
+	"time"
 )
 
 const (
 	FACTRGX = `#{(.*?)}`
 )
 
+// KnowledgeService stores the facts and relationships known to an operation and
+// resolves fact placeholders in commands against them.
 type KnowledgeService struct {
-	Logger *logger.Logger
+	Logger        *logger.Logger
+	facts         map[string][]*secondclass.Fact
+	relationships []*secondclass.Relationship
+	mutex         sync.RWMutex
+}
+
+// Combination is a command with its placeholders replaced, together with the
+// facts that were used to replace them.
+type Combination struct {
+	Command string
+	Used    []*secondclass.Fact
+}
+
+// FactCriteria selects facts in GetFacts. Empty fields match any fact.
+type FactCriteria struct {
+	Trait       string
+	Value       string
+	Origin      string
+	CollectedBy string
+	MinScore    int
+}
+
+// RelationshipCriteria selects relationships in GetRelationships. Empty fields match any relationship.
+type RelationshipCriteria struct {
+	Source *secondclass.Fact
+	Edge   string
+	Target *secondclass.Fact
+	// SourceTrait and TargetTrait match by trait only, when no exact fact is given.
+	SourceTrait string
+	TargetTrait string
 }
 
 func NewKnowledgeService(logger *logger.Logger) *KnowledgeService {
-	return &KnowledgeService{Logger: logger}
+	return &KnowledgeService{
+		Logger:        logger,
+		facts:         map[string][]*secondclass.Fact{},
+		relationships: []*secondclass.Relationship{},
+	}
+}
+
+// AddFact stores a fact and returns the stored instance. When a fact with the
+// same trait and value is already known, the collecting links are merged into
+// it and false is returned.
+func (ks *KnowledgeService) AddFact(fact *secondclass.Fact) (*secondclass.Fact, bool) {
+	ks.mutex.Lock()
+	defer ks.mutex.Unlock()
+	return ks.addFact(fact)
+}
+
+func (ks *KnowledgeService) addFact(fact *secondclass.Fact) (*secondclass.Fact, bool) {
+	now := time.Now().UTC()
+	for _, known := range ks.facts[fact.Trait] {
+		if known.Equal(fact) {
+			for _, linkId := range fact.CollectedBy {
+				if !slices.Contains(known.CollectedBy, linkId) {
+					known.CollectedBy = append(known.CollectedBy, linkId)
+				}
+			}
+			known.Updated = now
+			return known, false
+		}
+	}
+	if fact.Unique == "" {
+		fact.Unique = fact.Trait + fact.Value
+	}
+	if fact.Origin == "" {
+		fact.Origin = secondclass.IMPORTED
+	}
+	if fact.Created.IsZero() {
+		fact.Created = now
+	}
+	fact.Updated = now
+	ks.facts[fact.Trait] = append(ks.facts[fact.Trait], fact)
+	ks.Logger.Log(logger.TRACE, "Stored fact #{%s} = %s (%s)", fact.Trait, fact.Value, fact.Origin)
+	return fact, true
+}
+
+// AddRelationship stores a relationship and the facts on both of its ends.
+// It reports whether the relationship was new.
+func (ks *KnowledgeService) AddRelationship(relationship *secondclass.Relationship) bool {
+	ks.mutex.Lock()
+	defer ks.mutex.Unlock()
+	if relationship.Source == nil {
+		return false
+	}
+	relationship.Source, _ = ks.addFact(relationship.Source)
+	if relationship.Target != nil {
+		relationship.Target, _ = ks.addFact(relationship.Target)
+	}
+	for _, known := range ks.relationships {
+		if known.Equal(relationship) {
+			return false
+		}
+	}
+	if relationship.Origin == "" {
+		relationship.Origin = relationship.Source.Origin
+	}
+	ks.relationships = append(ks.relationships, relationship)
+	return true
+}
+
+// AddUsage records that a link used the given facts.
+func (ks *KnowledgeService) AddUsage(linkId string, used []*secondclass.Fact) {
+	ks.mutex.Lock()
+	defer ks.mutex.Unlock()
+	for _, fact := range used {
+		for _, known := range ks.facts[fact.Trait] {
+			if known.Equal(fact) && !slices.Contains(known.Links, linkId) {
+				known.Links = append(known.Links, linkId)
+			}
+		}
+	}
+}
+
+// GetFacts returns the facts matching the criteria, in insertion order per trait.
+func (ks *KnowledgeService) GetFacts(criteria FactCriteria) []*secondclass.Fact {
+	ks.mutex.RLock()
+	defer ks.mutex.RUnlock()
+	matches := []*secondclass.Fact{}
+	for _, trait := range ks.traits() {
+		if criteria.Trait != "" && trait != criteria.Trait {
+			continue
+		}
+		for _, fact := range ks.facts[trait] {
+			if criteria.Value != "" && fact.Value != criteria.Value {
+				continue
+			}
+			if criteria.Origin != "" && fact.Origin != criteria.Origin {
+				continue
+			}
+			if criteria.CollectedBy != "" && !slices.Contains(fact.CollectedBy, criteria.CollectedBy) {
+				continue
+			}
+			if fact.Score < criteria.MinScore {
+				continue
+			}
+			matches = append(matches, fact)
+		}
+	}
+	return matches
+}
+
+// GetRelationships returns the relationships matching the criteria.
+func (ks *KnowledgeService) GetRelationships(criteria RelationshipCriteria) []*secondclass.Relationship {
+	ks.mutex.RLock()
+	defer ks.mutex.RUnlock()
+	matches := []*secondclass.Relationship{}
+	for _, r := range ks.relationships {
+		if criteria.Source != nil && !r.Source.Equal(criteria.Source) {
+			continue
+		}
+		if criteria.SourceTrait != "" && r.Source.Trait != criteria.SourceTrait {
+			continue
+		}
+		if criteria.Edge != "" && r.Edge != criteria.Edge {
+			continue
+		}
+		if criteria.Target != nil && !r.Target.Equal(criteria.Target) {
+			continue
+		}
+		if criteria.TargetTrait != "" && (r.Target == nil || r.Target.Trait != criteria.TargetTrait) {
+			continue
+		}
+		matches = append(matches, r)
+	}
+	return matches
+}
+
+// Facts returns a snapshot of the known facts grouped by trait.
+func (ks *KnowledgeService) Facts() map[string][]*secondclass.Fact {
+	ks.mutex.RLock()
+	defer ks.mutex.RUnlock()
+	facts := make(map[string][]*secondclass.Fact, len(ks.facts))
+	for trait, values := range ks.facts {
+		facts[trait] = slices.Clone(values)
+	}
+	return facts
+}
+
+// traits returns the known traits in a stable order.
+func (ks *KnowledgeService) traits() []string {
+	traits := make([]string, 0, len(ks.facts))
+	for trait := range ks.facts {
+		traits = append(traits, trait)
+	}
+	slices.Sort(traits)
+	return traits
 }
 
 func (ks *KnowledgeService) RequiredTraits(command string) []string {
@@ -25,7 +215,7 @@ func (ks *KnowledgeService) RequiredTraits(command string) []string {
 
 	// Print extracted values
 	for _, match := range matches {
-		if len(match) > 1 {
+		if len(match) > 1 && !slices.Contains(traits, match[1]) {
 			ks.Logger.Log(logger.TRACE, "Command %s requires fact: #{%s}", command, match[1])
 			traits = append(traits, match[1])
 		}
@@ -33,15 +223,18 @@ func (ks *KnowledgeService) RequiredTraits(command string) []string {
 	return traits
 }
 
-func GenerateCombinations(keys []string, facts map[string][]*secondclass.Fact, index int, current map[string]*secondclass.Fact, results *[]string, template string) {
+func GenerateCombinations(keys []string, facts map[string][]*secondclass.Fact, index int, current map[string]*secondclass.Fact, results *[]Combination, template string) {
 	// Base case: all keys are replaced
 	if index == len(keys) {
 		// Replace placeholders in the template
 		finalStr := template
-		for key, fact := range current {
-			finalStr = regexp.MustCompile(`#{`+key+`}`).ReplaceAllLiteralString(finalStr, fact.Value)
+		used := []*secondclass.Fact{}
+		for _, key := range keys {
+			fact := current[key]
+			finalStr = regexp.MustCompile(`#{`+regexp.QuoteMeta(key)+`}`).ReplaceAllLiteralString(finalStr, fact.Value)
+			used = append(used, fact)
 		}
-		*results = append(*results, finalStr)
+		*results = append(*results, Combination{Command: finalStr, Used: used})
 		return
 	}
 
@@ -56,9 +249,47 @@ func GenerateCombinations(keys []string, facts map[string][]*secondclass.Fact, i
 	}
 }
 
-func (ks *KnowledgeService) ReplaceFacts(command string, facts map[string][]*secondclass.Fact) []string {
+// ReplaceFacts returns every combination of known facts that fills the placeholders of the command.
+func (ks *KnowledgeService) ReplaceFacts(command string) []Combination {
 	requiredTraits := ks.RequiredTraits(command)
-	var results []string
-	GenerateCombinations(requiredTraits, facts, 0, make(map[string]*secondclass.Fact), &results, command)
+	var results []Combination
+	GenerateCombinations(requiredTraits, ks.Facts(), 0, make(map[string]*secondclass.Fact), &results, command)
 	return results
 }
+
+// ReplaceFactsWithUsed fills the placeholders of a template with the facts a link
+// used first, then expands any remaining placeholders with the known facts.
+func (ks *KnowledgeService) ReplaceFactsWithUsed(template string, used []*secondclass.Fact) []Combination {
+	for _, fact := range used {
+		template = strings.ReplaceAll(template, "#{"+fact.Trait+"}", fact.Value)
+	}
+	combinations := ks.ReplaceFacts(template)
+	for i := range combinations {
+		combinations[i].Used = append(slices.Clone(used), combinations[i].Used...)
+	}
+	return combinations
+}
+
+// DumpToFile writes the known facts and relationships to a JSON file.
+func (ks *KnowledgeService) DumpToFile(filename string) error {
+	ks.mutex.RLock()
+	defer ks.mutex.RUnlock()
+
+	file, err := os.Create(filename)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	facts := []*secondclass.Fact{}
+	for _, trait := range ks.traits() {
+		facts = append(facts, ks.facts[trait]...)
+	}
+
+	encoder := json.NewEncoder(file)
+	encoder.SetIndent("", "  ")
+	return encoder.Encode(map[string]interface{}{
+		"facts":         facts,
+		"relationships": ks.relationships,
+	})
+}
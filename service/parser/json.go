@@ -0,0 +1,155 @@
+package parser
+
+import (
+	"calderat/secondclass"
+	"calderat/utils/logger"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// Json extracts facts from JSON output using dotted paths given in
+// custom_parser_vals.path (source) and custom_parser_vals.target_path (target).
+// A "*" path element walks every item of an array or every value of an object,
+// e.g. "users.*.name", in key order for objects. Source and target values are
+// paired within each element their paths share, e.g. users.*.name and users.*.uid.
+type Json struct {
+	shortName string
+	logger    *logger.Logger
+}
+
+// NewJson initializes a new JSON parser
+func NewJson(log *logger.Logger) *Json {
+	return &Json{
+		shortName: "json",
+		logger:    log,
+	}
+}
+
+func (jp *Json) Parse(output string, config secondclass.ParserConfig) ([]secondclass.Relationship, error) {
+	path, exists := config.CustomParserVals["path"]
+	if !exists || path == "" {
+		return nil, errors.New("missing 'path' in custom_parser_vals")
+	}
+
+	var document interface{}
+	if err := json.Unmarshal([]byte(output), &document); err != nil {
+		return nil, fmt.Errorf("output is not valid JSON: %w", err)
+	}
+
+	sourcePath := splitPath(path)
+	targetPath := []string{}
+	if config.Target != "" {
+		targetPath = splitPath(config.CustomParserVals["target_path"])
+	}
+	// Source and target are read from the same element of the last wildcard
+	// they share, so an element missing one of them does not shift the pairs.
+	prefix := sharedWildcardPrefix(sourcePath, targetPath)
+	relationships := []secondclass.Relationship{}
+	for _, element := range matchPath(document, prefix) {
+		sources := lookupPath(element, sourcePath[len(prefix):])
+		targets := []string{}
+		if len(targetPath) > 0 {
+			targets = lookupPath(element, targetPath[len(prefix):])
+		}
+		relationships = append(relationships, pairValues(sources, targets, config)...)
+	}
+	return relationships, nil
+}
+
+func (jp *Json) ShortName() string {
+	return jp.shortName
+}
+
+func splitPath(path string) []string {
+	path = strings.ReplaceAll(path, "[]", ".*")
+	elements := []string{}
+	for _, element := range strings.Split(path, ".") {
+		if element != "" && element != "$" {
+			elements = append(elements, element)
+		}
+	}
+	return elements
+}
+
+// sharedWildcardPrefix returns the path elements source and target share, up
+// to and including their last shared "*". Without a target there is no prefix.
+func sharedWildcardPrefix(sourcePath, targetPath []string) []string {
+	length := 0
+	for i := 0; i < len(sourcePath)-1 && i < len(targetPath)-1 && sourcePath[i] == targetPath[i]; i++ {
+		if sourcePath[i] == "*" {
+			length = i + 1
+		}
+	}
+	return sourcePath[:length]
+}
+
+// matchPath returns every node found at the given path.
+func matchPath(node interface{}, path []string) []interface{} {
+	nodes := []interface{}{node}
+	for _, element := range path {
+		matched := []interface{}{}
+		for _, current := range nodes {
+			matched = append(matched, children(current, element)...)
+		}
+		nodes = matched
+	}
+	return nodes
+}
+
+// children returns the child of a node named by a path element, or every child
+// for "*". Object keys are walked in sorted order so that results are stable.
+func children(node interface{}, element string) []interface{} {
+	switch current := node.(type) {
+	case map[string]interface{}:
+		if element != "*" {
+			if child, exists := current[element]; exists {
+				return []interface{}{child}
+			}
+			return nil
+		}
+		keys := make([]string, 0, len(current))
+		for key := range current {
+			keys = append(keys, key)
+		}
+		sort.Strings(keys)
+		values := make([]interface{}, 0, len(keys))
+		for _, key := range keys {
+			values = append(values, current[key])
+		}
+		return values
+	case []interface{}:
+		if element == "*" {
+			return current
+		}
+		if index, err := strconv.Atoi(element); err == nil && index >= 0 && index < len(current) {
+			return []interface{}{current[index]}
+		}
+	}
+	return nil
+}
+
+// lookupPath returns the string form of every value found at the given path.
+func lookupPath(node interface{}, path []string) []string {
+	values := []string{}
+	for _, match := range matchPath(node, path) {
+		switch value := match.(type) {
+		case nil:
+		case string:
+			values = append(values, value)
+		case float64:
+			values = append(values, strconv.FormatFloat(value, 'f', -1, 64))
+		case bool:
+			values = append(values, strconv.FormatBool(value))
+		default:
+			encoded, err := json.Marshal(value)
+			if err == nil {
+				values = append(values, string(encoded))
+			}
+		}
+	}
+	return values
+}
@@ -0,0 +1,57 @@
+package parser
+
+import (
+	"calderat/secondclass"
+	"calderat/utils/logger"
+	"errors"
+	"strings"
+)
+
+// KeyValue extracts facts from "key=value" lines. custom_parser_vals.key selects
+// the key holding the source value and custom_parser_vals.target_key the key
+// holding the target value. The separator defaults to "=" and can be changed
+// with custom_parser_vals.separator (e.g. ":" for "Name: value" output).
+// Surrounding quotes are removed from values.
+type KeyValue struct {
+	shortName string
+	logger    *logger.Logger
+}
+
+// NewKeyValue initializes a new key=value parser
+func NewKeyValue(log *logger.Logger) *KeyValue {
+	return &KeyValue{
+		shortName: "keyvalue",
+		logger:    log,
+	}
+}
+
+func (kp *KeyValue) Parse(output string, config secondclass.ParserConfig) ([]secondclass.Relationship, error) {
+	key, exists := config.CustomParserVals["key"]
+	if !exists || key == "" {
+		return nil, errors.New("missing 'key' in custom_parser_vals")
+	}
+	targetKey := config.CustomParserVals["target_key"]
+	separator := config.CustomParserVals["separator"]
+	if separator == "" {
+		separator = "="
+	}
+
+	sources, targets := []string{}, []string{}
+	for _, line := range strings.Split(output, "\n") {
+		k, v, found := strings.Cut(line, separator)
+		if !found {
+			continue
+		}
+		k, v = strings.TrimSpace(k), strings.Trim(strings.TrimSpace(v), `"'`)
+		if strings.EqualFold(k, key) {
+			sources = append(sources, v)
+		} else if targetKey != "" && strings.EqualFold(k, targetKey) {
+			targets = append(targets, v)
+		}
+	}
+	return pairValues(sources, targets, config), nil
+}
+
+func (kp *KeyValue) ShortName() string {
+	return kp.shortName
+}
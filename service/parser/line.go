@@ -0,0 +1,36 @@
+package parser
+
+import (
+	"calderat/secondclass"
+	"calderat/utils/logger"
+	"strings"
+)
+
+// Line creates one source fact for every non-empty line of output.
+type Line struct {
+	shortName string
+	logger    *logger.Logger
+}
+
+// NewLine initializes a new line parser
+func NewLine(log *logger.Logger) *Line {
+	return &Line{
+		shortName: "line",
+		logger:    log,
+	}
+}
+
+func (lp *Line) Parse(output string, config secondclass.ParserConfig) ([]secondclass.Relationship, error) {
+	values := []string{}
+	for _, line := range strings.Split(output, "\n") {
+		line = strings.TrimSpace(line)
+		if line != "" {
+			values = append(values, line)
+		}
+	}
+	return pairValues(values, nil, config), nil
+}
+
+func (lp *Line) ShortName() string {
+	return lp.shortName
+}
@@ -0,0 +1,92 @@
+package parser
+
+import (
+	"calderat/secondclass"
+	"calderat/utils/logger"
+	"fmt"
+	"strings"
+)
+
+// ParsingService extracts relationships (and therefore facts) from the output
+// of a link according to a single parser configuration.
+type ParsingService interface {
+	Parse(output string, config secondclass.ParserConfig) ([]secondclass.Relationship, error)
+	ShortName() string
+}
+
+// NewParsingService returns the parsing service registered for the given module.
+// Caldera module paths such as plugins.stockpile.app.parsers.basic are accepted
+// as aliases of the built-in parsers.
+func NewParsingService(module string, log *logger.Logger) (ParsingService, error) {
+	switch shortModuleName(module) {
+	case "regex":
+		return NewRegex(log), nil
+	case "line", "basic":
+		return NewLine(log), nil
+	case "json":
+		return NewJson(log), nil
+	case "keyvalue", "key_value":
+		return NewKeyValue(log), nil
+	default:
+		return nil, fmt.Errorf("unknown parser module: %s", module)
+	}
+}
+
+func shortModuleName(module string) string {
+	module = strings.ToLower(strings.TrimSpace(module))
+	if index := strings.LastIndex(module, "."); index >= 0 {
+		module = module[index+1:]
+	}
+	return module
+}
+
+// ParseOutput runs every parser of an executor against the output of a link and
+// returns the relationships they produced.
+func ParseOutput(output string, parsers []secondclass.Parser, log *logger.Logger) []secondclass.Relationship {
+	relationships := []secondclass.Relationship{}
+	for _, p := range parsers {
+		parsingService, err := NewParsingService(p.Module, log)
+		if err != nil {
+			log.Log(logger.WARN, "Skipping parser: %v", err)
+			continue
+		}
+		for _, config := range p.ParserConfigs {
+			parsed, err := parsingService.Parse(output, config)
+			if err != nil {
+				log.Log(logger.WARN, "Parser %s failed for trait %s: %v", parsingService.ShortName(), config.Source, err)
+				continue
+			}
+			log.Log(logger.TRACE, "Parser %s produced %d relationships for trait %s", parsingService.ShortName(), len(parsed), config.Source)
+			relationships = append(relationships, parsed...)
+		}
+	}
+	return relationships
+}
+
+// pairValues builds relationships from extracted source and target values.
+// Values are paired by index; a single target value is paired with every source.
+func pairValues(sources, targets []string, config secondclass.ParserConfig) []secondclass.Relationship {
+	relationships := []secondclass.Relationship{}
+	for i, source := range sources {
+		if source == "" {
+			continue
+		}
+		relationship := secondclass.Relationship{
+			Source: secondclass.NewFact(config.Source, source),
+		}
+		if config.Target != "" {
+			target := ""
+			if len(targets) == 1 {
+				target = targets[0]
+			} else if i < len(targets) {
+				target = targets[i]
+			}
+			if target != "" {
+				relationship.Edge = config.Edge
+				relationship.Target = secondclass.NewFact(config.Target, target)
+			}
+		}
+		relationships = append(relationships, relationship)
+	}
+	return relationships
+}
@@ -0,0 +1,65 @@
+package parser
+
+import (
+	"calderat/secondclass"
+	"calderat/utils/logger"
+	"errors"
+	"fmt"
+	"regexp"
+	"slices"
+)
+
+// Regex extracts facts with a regular expression given in custom_parser_vals.regex.
+// The named groups "source" and "target" hold the values of the source and
+// target facts. Without named groups the first two capture groups are used.
+// Without a source group, named or positional, the whole match becomes the
+// source value.
+type Regex struct {
+	shortName string
+	logger    *logger.Logger
+}
+
+// NewRegex initializes a new regex parser
+func NewRegex(log *logger.Logger) *Regex {
+	return &Regex{
+		shortName: "regex",
+		logger:    log,
+	}
+}
+
+func (rp *Regex) Parse(output string, config secondclass.ParserConfig) ([]secondclass.Relationship, error) {
+	pattern, exists := config.CustomParserVals["regex"]
+	if !exists || pattern == "" {
+		return nil, errors.New("missing 'regex' in custom_parser_vals")
+	}
+	re, err := regexp.Compile(pattern)
+	if err != nil {
+		return nil, fmt.Errorf("invalid regex %q: %w", pattern, err)
+	}
+
+	sourceIndex, targetIndex := re.SubexpIndex("source"), re.SubexpIndex("target")
+	named := slices.ContainsFunc(re.SubexpNames(), func(name string) bool { return name != "" })
+	if !named && re.NumSubexp() > 0 {
+		sourceIndex = 1
+		if re.NumSubexp() > 1 {
+			targetIndex = 2
+		}
+	}
+	if sourceIndex < 0 {
+		sourceIndex = 0
+	}
+
+	relationships := []secondclass.Relationship{}
+	for _, match := range re.FindAllStringSubmatch(output, -1) {
+		targets := []string{}
+		if targetIndex > 0 {
+			targets = append(targets, match[targetIndex])
+		}
+		relationships = append(relationships, pairValues([]string{match[sourceIndex]}, targets, config)...)
+	}
+	return relationships, nil
+}
+
+func (rp *Regex) ShortName() string {
+	return rp.shortName
+}
@@ -0,0 +1,34 @@
+package requirement
+
+import (
+	"calderat/secondclass"
+	"calderat/service/knowledge"
+	"calderat/utils/logger"
+)
+
+// Basic requires the source fact used by the link to have a relationship with
+// the given edge. When a target trait is given the relationship must point at
+// a fact of that trait, and at the used one if the link uses that trait.
+type Basic struct {
+	shortName string
+	logger    *logger.Logger
+}
+
+// NewBasic initializes a new basic requirement
+func NewBasic(log *logger.Logger) *Basic {
+	return &Basic{
+		shortName: "basic",
+		logger:    log,
+	}
+}
+
+func (b *Basic) Enforce(match secondclass.RelationshipMatch, used []*secondclass.Fact, knowledgeService *knowledge.KnowledgeService) bool {
+	if usedFact(used, match.Source) == nil {
+		return false
+	}
+	return len(matchingRelationships(match, used, knowledgeService)) > 0
+}
+
+func (b *Basic) ShortName() string {
+	return b.shortName
+}
@@ -0,0 +1,34 @@
+package requirement
+
+import (
+	"calderat/secondclass"
+	"calderat/service/knowledge"
+	"calderat/utils/logger"
+)
+
+// Existential requires the knowledge to hold a fact of the source trait and,
+// when an edge is given, a relationship with that edge. Unlike basic it does not
+// need the link to use the source fact.
+type Existential struct {
+	shortName string
+	logger    *logger.Logger
+}
+
+// NewExistential initializes a new existential requirement
+func NewExistential(log *logger.Logger) *Existential {
+	return &Existential{
+		shortName: "existential",
+		logger:    log,
+	}
+}
+
+func (e *Existential) Enforce(match secondclass.RelationshipMatch, used []*secondclass.Fact, knowledgeService *knowledge.KnowledgeService) bool {
+	if match.Edge == "" && match.Target == "" {
+		return len(knowledgeService.GetFacts(knowledge.FactCriteria{Trait: match.Source})) > 0
+	}
+	return len(matchingRelationships(match, used, knowledgeService)) > 0
+}
+
+func (e *Existential) ShortName() string {
+	return e.shortName
+}
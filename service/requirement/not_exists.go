@@ -0,0 +1,34 @@
+package requirement
+
+import (
+	"calderat/secondclass"
+	"calderat/service/knowledge"
+	"calderat/utils/logger"
+)
+
+// NotExists is the negation of existential: it passes only while the knowledge
+// holds no matching relationship (or, without an edge, no fact of the source
+// trait). It is used to avoid repeating work that already produced its facts.
+type NotExists struct {
+	shortName string
+	logger    *logger.Logger
+}
+
+// NewNotExists initializes a new not_exists requirement
+func NewNotExists(log *logger.Logger) *NotExists {
+	return &NotExists{
+		shortName: "not_exists",
+		logger:    log,
+	}
+}
+
+func (ne *NotExists) Enforce(match secondclass.RelationshipMatch, used []*secondclass.Fact, knowledgeService *knowledge.KnowledgeService) bool {
+	if match.Edge == "" && match.Target == "" {
+		return len(knowledgeService.GetFacts(knowledge.FactCriteria{Trait: match.Source})) == 0
+	}
+	return len(matchingRelationships(match, used, knowledgeService)) == 0
+}
+
+func (ne *NotExists) ShortName() string {
+	return ne.shortName
+}
@@ -0,0 +1,34 @@
+package requirement
+
+import (
+	"calderat/secondclass"
+	"calderat/service/knowledge"
+	"calderat/utils/logger"
+)
+
+// Paired requires the link to use both a source and a target fact, and those two
+// facts to be connected by the given edge. It stops commands such as
+// `ping #{ip} -c #{count}` from running with values that were never discovered together.
+type Paired struct {
+	shortName string
+	logger    *logger.Logger
+}
+
+// NewPaired initializes a new paired requirement
+func NewPaired(log *logger.Logger) *Paired {
+	return &Paired{
+		shortName: "paired",
+		logger:    log,
+	}
+}
+
+func (p *Paired) Enforce(match secondclass.RelationshipMatch, used []*secondclass.Fact, knowledgeService *knowledge.KnowledgeService) bool {
+	if usedFact(used, match.Source) == nil || (match.Target != "" && usedFact(used, match.Target) == nil) {
+		return false
+	}
+	return len(matchingRelationships(match, used, knowledgeService)) > 0
+}
+
+func (p *Paired) ShortName() string {
+	return p.shortName
+}
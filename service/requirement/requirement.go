@@ -0,0 +1,80 @@
+package requirement
+
+import (
+	"calderat/secondclass"
+	"calderat/service/knowledge"
+	"calderat/utils/logger"
+	"fmt"
+	"strings"
+)
+
+// RequirementService decides whether a combination of facts used by a link
+// satisfies a single relationship match of an ability requirement.
+type RequirementService interface {
+	Enforce(match secondclass.RelationshipMatch, used []*secondclass.Fact, knowledgeService *knowledge.KnowledgeService) bool
+	ShortName() string
+}
+
+// NewRequirementService returns the requirement service registered for the given
+// module. Both Caldera module paths (plugins.stockpile.app.requirements.paired)
+// and short names (paired) are accepted.
+func NewRequirementService(module string, log *logger.Logger) (RequirementService, error) {
+	name := strings.ToLower(strings.TrimSpace(module))
+	if index := strings.LastIndex(name, "."); index >= 0 {
+		name = name[index+1:]
+	}
+	switch name {
+	case "basic":
+		return NewBasic(log), nil
+	case "paired":
+		return NewPaired(log), nil
+	case "not_exists":
+		return NewNotExists(log), nil
+	case "existential":
+		return NewExistential(log), nil
+	default:
+		return nil, fmt.Errorf("unknown requirement module: %s", module)
+	}
+}
+
+// Enforce reports whether the used facts satisfy every relationship match of
+// every requirement. Unknown requirement modules fail closed.
+func Enforce(requirements []secondclass.Requirement, used []*secondclass.Fact, knowledgeService *knowledge.KnowledgeService, log *logger.Logger) bool {
+	for _, requirement := range requirements {
+		requirementService, err := NewRequirementService(requirement.Module, log)
+		if err != nil {
+			log.Log(logger.WARN, "Requirement not satisfied: %v", err)
+			return false
+		}
+		for _, match := range requirement.RelationshipMatch {
+			if !requirementService.Enforce(match, used, knowledgeService) {
+				log.Log(logger.TRACE, "Requirement %s not satisfied for %s -%s-> %s", requirementService.ShortName(), match.Source, match.Edge, match.Target)
+				return false
+			}
+		}
+	}
+	return true
+}
+
+// usedFact returns the fact with the given trait used by a link, or nil.
+func usedFact(used []*secondclass.Fact, trait string) *secondclass.Fact {
+	for _, fact := range used {
+		if fact.Trait == trait {
+			return fact
+		}
+	}
+	return nil
+}
+
+// matchingRelationships returns the known relationships whose source trait, edge
+// and target trait agree with the match. When the link uses a fact of the source
+// or target trait, the relationship must point at that exact fact.
+func matchingRelationships(match secondclass.RelationshipMatch, used []*secondclass.Fact, knowledgeService *knowledge.KnowledgeService) []*secondclass.Relationship {
+	return knowledgeService.GetRelationships(knowledge.RelationshipCriteria{
+		Source:      usedFact(used, match.Source),
+		SourceTrait: match.Source,
+		Edge:        match.Edge,
+		Target:      usedFact(used, match.Target),
+		TargetTrait: match.Target,
+	})
+}
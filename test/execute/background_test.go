@@ -0,0 +1,51 @@
+package execute_test
+
+import (
+	"calderat/service/execute"
+	"calderat/utils/logger"
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestStartInBackground(t *testing.T) {
+	log, _ := logger.New("DEBUG")
+	start := time.Now()
+	handle := execute.Start(context.Background(), execute.NewSh(log), execute.Request{Command: "echo listening; sleep 30", Timeout: time.Minute})
+	if !handle.Running() || handle.Pid == 0 {
+		t.Fatalf("Expected a running command with a pid, got pid %d", handle.Pid)
+	}
+	if elapsed := time.Since(start); elapsed > 5*time.Second {
+		t.Errorf("Expected Start to return once the command started, took %v", elapsed)
+	}
+	time.Sleep(200 * time.Millisecond)
+
+	result, err := handle.Stop()
+	if !errors.Is(err, execute.ErrInterrupted) || !handle.Stopped() {
+		t.Errorf("Expected the command to be stopped, got: %v", err)
+	}
+	if result.Stdout != "listening\n" || result.Pid != handle.Pid {
+		t.Errorf("Expected the output of process %d to be collected, got %q from %d", handle.Pid, result.Stdout, result.Pid)
+	}
+	if elapsed := time.Since(start); elapsed > 10*time.Second {
+		t.Errorf("Expected the command to stop soon after Stop, took %v", elapsed)
+	}
+}
+
+func TestStartFinished(t *testing.T) {
+	log, _ := logger.New("DEBUG")
+	handle := execute.Start(context.Background(), execute.NewShell("missing", "/nonexistent/shell", nil, log), execute.Request{Command: "true", Timeout: time.Second})
+	if handle.Running() || handle.Pid != 0 {
+		t.Fatalf("Expected a command that did not start, got pid %d", handle.Pid)
+	}
+	if _, err := handle.Wait(); !errors.Is(err, execute.ErrExecutorUnavailable) {
+		t.Errorf("Expected executor unavailable error, got: %v", err)
+	}
+
+	handle = execute.Start(context.Background(), execute.NewSh(log), execute.Request{Command: "exit 0", Timeout: time.Second})
+	<-handle.Done()
+	if _, err := handle.Stop(); err != nil || handle.Stopped() {
+		t.Errorf("Expected a command that ended by itself not to be stopped, got: %v", err)
+	}
+}
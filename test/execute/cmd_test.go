@@ -3,6 +3,7 @@ package execute
 import (
 	"calderat/service/execute"
 	"calderat/utils/logger"
+	"context"
 	"runtime"
 	"testing"
 	"time"
@@ -20,10 +21,10 @@ func TestExecuteSuccess(t *testing.T) {
 	cmdExecutor := execute.NewCmd(log)
 
 	// Simple command to test (verifying it doesn't fail)
-	output, err := cmdExecutor.Execute(`dir /s c:\`, 60*time.Second)
+	result, err := cmdExecutor.Execute(context.Background(), execute.Request{Command: `dir /s c:\`, Timeout: 60 * time.Second})
 	if err != nil {
 		t.Fatalf("Expected no error, but got: %v", err)
 	}
 
-	t.Log(output)
+	t.Log(result.Stdout)
 }
@@ -0,0 +1,60 @@
+package execute_test
+
+import (
+	"calderat/service/execute"
+	"calderat/utils/logger"
+	"context"
+	"os"
+	"os/exec"
+	"runtime"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestCodeExecuteScript(t *testing.T) {
+	if runtime.GOOS != "linux" {
+		t.Skip("Skipping test: sh scripts only run on Linux")
+	}
+	log, _ := logger.New("DEBUG")
+	dir := t.TempDir()
+	code := execute.NewCode("sh", "", log)
+	code.SetWorkingDir(dir)
+
+	result, err := code.Execute(context.Background(), execute.Request{Command: "echo from script; pwd", Timeout: 10 * time.Second})
+	if err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+	if !strings.Contains(result.Stdout, "from script") || !strings.Contains(result.Stdout, dir) {
+		t.Errorf("Unexpected output: %q", result.Stdout)
+	}
+
+	entries, _ := os.ReadDir(dir)
+	if len(entries) != 0 {
+		t.Errorf("Expected temporary code files to be removed, found %d entries", len(entries))
+	}
+}
+
+func TestCodeExecuteGoBuild(t *testing.T) {
+	if _, err := exec.LookPath("go"); err != nil {
+		t.Skip("Skipping test: no Go toolchain available")
+	}
+	log, _ := logger.New("DEBUG")
+	code := execute.NewCode("golang", "hello", log)
+	code.SetWorkingDir(t.TempDir())
+
+	result, err := code.Execute(context.Background(), execute.Request{Command: "package main\n\nimport \"fmt\"\n\nfunc main() { fmt.Println(\"built\") }\n", Timeout: 120 * time.Second})
+	if err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+	if strings.TrimSpace(result.Stdout) != "built" {
+		t.Errorf("Expected output 'built', got %q", result.Stdout)
+	}
+}
+
+func TestCodeUnsupportedLanguage(t *testing.T) {
+	log, _ := logger.New("DEBUG")
+	if _, err := execute.NewCode("cobol", "", log).Execute(context.Background(), execute.Request{Command: "DISPLAY 'HI'.", Timeout: time.Second}); err == nil {
+		t.Error("Expected error for unsupported language, got nil")
+	}
+}
@@ -0,0 +1,76 @@
+package execute_test
+
+import (
+	"calderat/service/execute"
+	"calderat/utils/logger"
+	"context"
+	"encoding/base64"
+	"strings"
+	"testing"
+	"time"
+)
+
+func runSh(t *testing.T, request execute.Request) execute.Result {
+	t.Helper()
+	log, _ := logger.New("ERROR")
+	request.Timeout = 5 * time.Second
+	result, err := execute.NewSh(log).Execute(context.Background(), request)
+	if err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+	return result
+}
+
+func TestOutputTruncated(t *testing.T) {
+	// 2000 lines of 5 bytes, of which the first and last 50 bytes are kept
+	result := runSh(t, execute.Request{Command: `i=0; while [ $i -lt 2000 ]; do printf '%04d\n' $i; i=$((i+1)); done`, OutputLimit: 100})
+	capture := result.StdoutCapture
+	if !capture.Truncated || capture.Bytes != 10000 || capture.Omitted != 9900 {
+		t.Fatalf("Expected 9900 of 10000 bytes omitted, got %+v", capture)
+	}
+	if !strings.HasPrefix(result.Stdout, "0000\n0001\n") || !strings.HasSuffix(result.Stdout, "1998\n1999\n") {
+		t.Errorf("Expected the head and tail of the output, got %q", result.Stdout)
+	}
+	if !strings.Contains(result.Stdout, "[... 9900 bytes omitted ...]") {
+		t.Errorf("Expected a truncation marker, got %q", result.Stdout)
+	}
+	if result.StderrCapture.Truncated || result.StderrCapture.Bytes != 0 {
+		t.Errorf("Expected empty stderr, got %+v", result.StderrCapture)
+	}
+}
+
+func TestOutputBinary(t *testing.T) {
+	result := runSh(t, execute.Request{Command: `printf '\177ELF\002\001\001\000\000\000'`})
+	if !result.StdoutCapture.Binary || result.StdoutCapture.Encoding != execute.BASE64 {
+		t.Fatalf("Expected binary output, got %+v", result.StdoutCapture)
+	}
+	data, err := base64.StdEncoding.DecodeString(result.Stdout)
+	if err != nil || string(data) != "\x7fELF\x02\x01\x01\x00\x00\x00" {
+		t.Errorf("Expected the base64 of the output, got %q (%v)", result.Stdout, err)
+	}
+}
+
+func TestOutputDecoding(t *testing.T) {
+	tests := []struct {
+		name     string
+		command  string
+		encoding string
+		expected string
+		detected string
+	}{
+		{"utf-8", `printf 'caf\303\251'`, "", "café", execute.UTF8},
+		{"utf-16le", `printf 'd\000i\000r\000\r\000\n\000'`, "", "dir\r\n", execute.UTF16LE},
+		{"utf-16le bom", `printf '\377\376O\000K\000'`, "", "OK", execute.UTF16LE},
+		{"cp437", `printf 'R\202sum\202 \315\315'`, execute.CP437, "Résumé ══", execute.CP437},
+		{"cp850", `printf 'Espa\244a \265'`, execute.CP850, "España Á", execute.CP850},
+		{"windows-1252", `printf '\223quoted\224 \200 \351'`, execute.WINDOWS1252, "“quoted” € é", execute.WINDOWS1252},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			result := runSh(t, execute.Request{Command: test.command, Encoding: test.encoding})
+			if result.Stdout != test.expected || result.StdoutCapture.Encoding != test.detected {
+				t.Errorf("Expected %q decoded from %s, got %q from %s", test.expected, test.detected, result.Stdout, result.StdoutCapture.Encoding)
+			}
+		})
+	}
+}
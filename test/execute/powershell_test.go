@@ -3,6 +3,7 @@ package execute_test
 import (
 	"calderat/service/execute"
 	"calderat/utils/logger"
+	"context"
 	"fmt"
 	"os"
 	"runtime"
@@ -38,7 +39,7 @@ func TestPowerShellExecute(t *testing.T) {
 
 	// Case 1: Unsupported OS
 	if runtime.GOOS != "windows" {
-		_, err := ps.Execute("Get-Date", 2*time.Second)
+		_, err := ps.Execute(context.Background(), execute.Request{Command: "Get-Date", Timeout: 2 * time.Second})
 		if err == nil || err.Error() != "PowerShell is only supported on Windows systems" {
 			t.Error("Expected unsupported OS error, got nil or wrong error")
 		}
@@ -46,20 +47,20 @@ func TestPowerShellExecute(t *testing.T) {
 	}
 
 	// Case 2: Successful Command Execution
-	output, err := ps.Execute("Write-Output 'Hello, PowerShell!'", 2*time.Second)
+	result, err := ps.Execute(context.Background(), execute.Request{Command: "Write-Output 'Hello, PowerShell!'", Timeout: 2 * time.Second})
 	if err != nil {
 		t.Errorf("Expected successful execution, got error: %v", err)
 	}
-	fmt.Println(output)
+	fmt.Println(result.Stdout)
 
 	expected := "Hello, PowerShell!"
 	fmt.Println(expected)
-	if output != expected {
-		t.Errorf("Expected output: '%s', got: '%s'", expected, output)
+	if result.Stdout != expected {
+		t.Errorf("Expected output: '%s', got: '%s'", expected, result.Stdout)
 	}
 
 	// Case 3: Timeout Scenario
-	_, err = ps.Execute("Start-Sleep -Seconds 5", 2*time.Second)
+	_, err = ps.Execute(context.Background(), execute.Request{Command: "Start-Sleep -Seconds 5", Timeout: 2 * time.Second})
 	if err == nil || !strings.Contains(err.Error(), "command timed out") {
 		t.Error("Expected timeout error, got nil or wrong error")
 	}
@@ -80,7 +81,7 @@ func TestPowerShellLogger(t *testing.T) {
 	ps := execute.NewPowerShell(log)
 
 	// Test logging interactions
-	ps.Execute("echo test", 2*time.Second)
+	ps.Execute(context.Background(), execute.Request{Command: "echo test", Timeout: 2 * time.Second})
 }
 
 func TestPowerShellTimeout(t *testing.T) {
@@ -88,7 +89,7 @@ func TestPowerShellTimeout(t *testing.T) {
 	ps := execute.NewPowerShell(log)
 
 	// Test timeout logic
-	_, err := ps.Execute("Start-Sleep -Seconds 5", 1*time.Second)
+	_, err := ps.Execute(context.Background(), execute.Request{Command: "Start-Sleep -Seconds 5", Timeout: 1 * time.Second})
 	if err == nil || !strings.Contains(err.Error(), "command timed out") {
 		t.Error("Expected timeout error, got nil or wrong error")
 	}
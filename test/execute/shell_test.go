@@ -0,0 +1,179 @@
+package execute_test
+
+import (
+	"calderat/service/execute"
+	"calderat/utils/logger"
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestBashExecute(t *testing.T) {
+	if _, err := exec.LookPath("bash"); err != nil {
+		t.Skip("Skipping test: bash is not installed")
+	}
+	log, _ := logger.New("DEBUG")
+	bash := execute.NewBash(log)
+	if bash.ShortName() != "bash" {
+		t.Errorf("Expected shortName to be 'bash', got '%s'", bash.ShortName())
+	}
+
+	// [[ ]] and arrays are bash-only syntax that dash rejects
+	result, err := bash.Execute(context.Background(), execute.Request{Command: `arr=(a b c); [[ ${#arr[@]} -eq 3 ]] && echo "${arr[1]}"`, Timeout: 5 * time.Second})
+	if err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+	if strings.TrimSpace(result.Stdout) != "b" {
+		t.Errorf("Expected output 'b', got %q", result.Stdout)
+	}
+}
+
+func TestPythonExecute(t *testing.T) {
+	if _, err := exec.LookPath("python3"); err != nil {
+		t.Skip("Skipping test: python3 is not installed")
+	}
+	log, _ := logger.New("DEBUG")
+	result, err := execute.NewPython("python3", log).Execute(context.Background(), execute.Request{Command: `print(6 * 7)`, Timeout: 5 * time.Second})
+	if err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+	if strings.TrimSpace(result.Stdout) != "42" {
+		t.Errorf("Expected output '42', got %q", result.Stdout)
+	}
+}
+
+func TestShellTimeout(t *testing.T) {
+	if _, err := exec.LookPath("bash"); err != nil {
+		t.Skip("Skipping test: bash is not installed")
+	}
+	log, _ := logger.New("DEBUG")
+	_, err := execute.NewBash(log).Execute(context.Background(), execute.Request{Command: "sleep 5", Timeout: 500 * time.Millisecond})
+	if err == nil || !strings.Contains(err.Error(), "command timed out") {
+		t.Errorf("Expected timeout error, got: %v", err)
+	}
+}
+
+func TestShellInterruptKillsProcessGroup(t *testing.T) {
+	if _, err := exec.LookPath("bash"); err != nil {
+		t.Skip("Skipping test: bash is not installed")
+	}
+	log, _ := logger.New("DEBUG")
+	pidFile := filepath.Join(t.TempDir(), "child.pid")
+	ctx, cancel := context.WithCancel(context.Background())
+	time.AfterFunc(500*time.Millisecond, cancel)
+
+	start := time.Now()
+	_, err := execute.NewBash(log).Execute(ctx, execute.Request{Command: fmt.Sprintf("sleep 30 & echo $! > %s; wait", pidFile), Timeout: 30 * time.Second})
+	if !errors.Is(err, execute.ErrInterrupted) {
+		t.Fatalf("Expected interrupted error, got: %v", err)
+	}
+	if elapsed := time.Since(start); elapsed > 10*time.Second {
+		t.Errorf("Expected the command to stop soon after cancel, took %v", elapsed)
+	}
+
+	pid, err := os.ReadFile(pidFile)
+	if err != nil {
+		t.Fatalf("Failed to read child pid: %v", err)
+	}
+	time.Sleep(100 * time.Millisecond)
+	stat, err := os.ReadFile(filepath.Join("/proc", strings.TrimSpace(string(pid)), "stat"))
+	if err == nil && !strings.Contains(string(stat), ") Z ") {
+		t.Errorf("Expected background child %s to be killed, still running: %s", strings.TrimSpace(string(pid)), stat)
+	}
+}
+
+func TestShellResult(t *testing.T) {
+	if _, err := exec.LookPath("bash"); err != nil {
+		t.Skip("Skipping test: bash is not installed")
+	}
+	log, _ := logger.New("DEBUG")
+	bash := execute.NewBash(log)
+
+	result, err := bash.Execute(context.Background(), execute.Request{Command: "echo partial; echo broken >&2; exit 3", Timeout: 5 * time.Second})
+	if err == nil {
+		t.Fatal("Expected error for non-zero exit code, got nil")
+	}
+	if result.ExitCode != 3 || result.Pid == 0 || result.Signal != "" {
+		t.Errorf("Expected exit code 3 with a pid and no signal, got %+v", result)
+	}
+	if result.Stdout != "partial\n" || result.Stderr != "broken\n" {
+		t.Errorf("Expected stdout and stderr kept separately on failure, got %q and %q", result.Stdout, result.Stderr)
+	}
+
+	result, err = bash.Execute(context.Background(), execute.Request{Command: "kill -TERM $$", Timeout: 5 * time.Second})
+	if err == nil {
+		t.Fatal("Expected error for signalled process, got nil")
+	}
+	if result.ExitCode != -1 || result.Signal != "terminated" {
+		t.Errorf("Expected process ended by SIGTERM, got %+v", result)
+	}
+}
+
+func TestShellStreamsLines(t *testing.T) {
+	if _, err := exec.LookPath("bash"); err != nil {
+		t.Skip("Skipping test: bash is not installed")
+	}
+	log, _ := logger.New("DEBUG")
+	lines := make(chan execute.Line, 16)
+	result, err := execute.NewBash(log).Execute(context.Background(), execute.Request{
+		Command: "echo one; echo two >&2; sleep 0.2; printf three",
+		Timeout: 5 * time.Second,
+		Lines:   lines,
+	})
+	close(lines)
+	if err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+
+	received := map[string][]string{}
+	for line := range lines {
+		received[line.Stream] = append(received[line.Stream], line.Text)
+	}
+	if strings.Join(received[execute.STDOUT], ",") != "one,three" || strings.Join(received[execute.STDERR], ",") != "two" {
+		t.Errorf("Unexpected streamed lines: %v", received)
+	}
+	if result.Stdout != "one\nthree" || result.Stderr != "two\n" {
+		t.Errorf("Expected full output to be captured too, got %q and %q", result.Stdout, result.Stderr)
+	}
+}
+
+func TestShellStdin(t *testing.T) {
+	log, _ := logger.New("DEBUG")
+	result, err := execute.NewSh(log).Execute(context.Background(), execute.Request{
+		Command: `read -r secret; echo "got $secret"; cat`,
+		Stdin:   "hunter2\nrest",
+		Timeout: 5 * time.Second,
+	})
+	if err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+	if result.Stdout != "got hunter2\nrest" {
+		t.Errorf("Expected the command to read stdin, got %q", result.Stdout)
+	}
+}
+
+func TestShellTerminal(t *testing.T) {
+	if runtime.GOOS != "linux" {
+		t.Skip("Skipping test: terminal mode is only supported on Linux")
+	}
+	log, _ := logger.New("DEBUG")
+	result, err := execute.NewSh(log).Execute(context.Background(), execute.Request{
+		Command: `[ -t 0 ] && [ -t 1 ] && echo tty; read -r secret; echo "got $secret" >&2; cat`,
+		Stdin:   "hunter2\nrest",
+		TTY:     true,
+		Timeout: 5 * time.Second,
+	})
+	if err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+	if result.Stdout != "tty\ngot hunter2\nrest" || result.Stderr != "" {
+		t.Errorf("Expected terminal output without echo on stdout, got %q and %q", result.Stdout, result.Stderr)
+	}
+}
@@ -0,0 +1,175 @@
+package file_test
+
+import (
+	"calderat/service/file"
+	"calderat/utils/logger"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func sha256Hex(content string) string {
+	sum := sha256.Sum256([]byte(content))
+	return hex.EncodeToString(sum[:])
+}
+
+func TestStageLocalPayload(t *testing.T) {
+	log, _ := logger.New("DEBUG")
+	payloadDir, workDir := t.TempDir(), t.TempDir()
+	os.WriteFile(filepath.Join(payloadDir, "tool.sh"), []byte("echo tool"), 0644)
+	os.WriteFile(filepath.Join(payloadDir, file.ManifestFile), []byte(fmt.Sprintf("tool.sh: %s\n", sha256Hex("echo tool"))), 0644)
+
+	fs, err := file.NewFileService(payloadDir, "", workDir, "", "", log)
+	if err != nil {
+		t.Fatalf("Failed to create file service: %v", err)
+	}
+	if err := fs.StagePayloads([]string{"tool.sh"}); err != nil {
+		t.Fatalf("Expected payload to be staged, got: %v", err)
+	}
+	if _, err := os.Stat(filepath.Join(workDir, "tool.sh")); err != nil {
+		t.Fatalf("Expected staged payload in working directory: %v", err)
+	}
+
+	fs.RemovePayloads([]string{"tool.sh"})
+	if _, err := os.Stat(filepath.Join(workDir, "tool.sh")); !os.IsNotExist(err) {
+		t.Error("Expected payload to be deleted")
+	}
+}
+
+func TestStageDownloadedPayload(t *testing.T) {
+	log, _ := logger.New("DEBUG")
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/remote.bin" {
+			w.Write([]byte("remote payload"))
+			return
+		}
+		http.NotFound(w, r)
+	}))
+	defer server.Close()
+
+	payloadDir, workDir := t.TempDir(), t.TempDir()
+	fs, err := file.NewFileService(payloadDir, server.URL, workDir, "", "", log)
+	if err != nil {
+		t.Fatalf("Failed to create file service: %v", err)
+	}
+	if err := fs.StagePayloads([]string{"remote.bin"}); err != nil {
+		t.Fatalf("Expected payload to be downloaded, got: %v", err)
+	}
+	content, _ := os.ReadFile(filepath.Join(workDir, "remote.bin"))
+	if string(content) != "remote payload" {
+		t.Errorf("Unexpected payload content: %q", content)
+	}
+
+	if err := fs.StagePayloads([]string{"missing.bin"}); err == nil {
+		t.Error("Expected error for missing payload, got nil")
+	}
+}
+
+func TestStagePayloadHashMismatch(t *testing.T) {
+	log, _ := logger.New("DEBUG")
+	payloadDir, workDir := t.TempDir(), t.TempDir()
+	os.WriteFile(filepath.Join(payloadDir, "tool.sh"), []byte("tampered"), 0644)
+	os.WriteFile(filepath.Join(payloadDir, file.ManifestFile), []byte(fmt.Sprintf("tool.sh: %s\n", sha256Hex("echo tool"))), 0644)
+
+	fs, err := file.NewFileService(payloadDir, "", workDir, "", "", log)
+	if err != nil {
+		t.Fatalf("Failed to create file service: %v", err)
+	}
+	if err := fs.StagePayloads([]string{"tool.sh"}); err == nil {
+		t.Fatal("Expected hash mismatch error, got nil")
+	}
+	if _, err := os.Stat(filepath.Join(workDir, "tool.sh")); !os.IsNotExist(err) {
+		t.Error("Expected mismatching payload to be removed")
+	}
+	if err := fs.StagePayloads([]string{"../escape"}); err == nil {
+		t.Error("Expected error for payload name outside the working directory")
+	}
+}
+
+func TestStagePayloadKeepsOperatorFiles(t *testing.T) {
+	log, _ := logger.New("DEBUG")
+	payloadDir, workDir := t.TempDir(), t.TempDir()
+	os.WriteFile(filepath.Join(payloadDir, "tool.sh"), []byte("echo tool"), 0644)
+	os.WriteFile(filepath.Join(payloadDir, "notes.txt"), []byte("payload notes"), 0644)
+	os.WriteFile(filepath.Join(workDir, "tool.sh"), []byte("echo tool"), 0644)
+	os.WriteFile(filepath.Join(workDir, "notes.txt"), []byte("operator notes"), 0644)
+
+	fs, err := file.NewFileService(payloadDir, "", workDir, "", "", log)
+	if err != nil {
+		t.Fatalf("Failed to create file service: %v", err)
+	}
+	if err := fs.StagePayloads([]string{"notes.txt"}); err == nil {
+		t.Error("Expected an error instead of replacing a file of the operator")
+	}
+	if err := fs.StagePayloads([]string{"tool.sh"}); err != nil {
+		t.Errorf("Expected a file of the operator that is the payload to be used, got: %v", err)
+	}
+	fs.RemovePayloads([]string{"tool.sh", "notes.txt"})
+	for name, content := range map[string]string{"tool.sh": "echo tool", "notes.txt": "operator notes"} {
+		if data, err := os.ReadFile(filepath.Join(workDir, name)); err != nil || string(data) != content {
+			t.Errorf("Expected %s of the operator to be kept, got %q (%v)", name, data, err)
+		}
+	}
+	if entries, _ := os.ReadDir(workDir); len(entries) != 2 {
+		t.Errorf("Expected no temporary files left in the working directory, got %d entries", len(entries))
+	}
+}
+
+func TestUploadFilesToArtifacts(t *testing.T) {
+	log, _ := logger.New("DEBUG")
+	workDir, artifactsDir := t.TempDir(), t.TempDir()
+	os.WriteFile(filepath.Join(workDir, "loot.txt"), []byte("secret"), 0644)
+
+	fs, err := file.NewFileService(t.TempDir(), "", workDir, artifactsDir, "", log)
+	if err != nil {
+		t.Fatalf("Failed to create file service: %v", err)
+	}
+	uploads := fs.UploadFiles([]string{"loot.txt", "missing.txt"}, "op-1", "link-1")
+	if len(uploads) != 2 {
+		t.Fatalf("Expected 2 upload records, got %d", len(uploads))
+	}
+	if uploads[0].Error != "" || uploads[0].Size != 6 || uploads[0].Sha256 != sha256Hex("secret") {
+		t.Errorf("Unexpected upload record: %+v", uploads[0])
+	}
+	if content, _ := os.ReadFile(filepath.Join(artifactsDir, "op-1", "link-1", "loot.txt")); string(content) != "secret" {
+		t.Errorf("Expected file copied to artifacts directory, got %q", content)
+	}
+	if uploads[1].Error == "" {
+		t.Error("Expected missing file to be recorded with an error")
+	}
+}
+
+func TestUploadFilesToURL(t *testing.T) {
+	log, _ := logger.New("DEBUG")
+	received := ""
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		upload, _, err := r.FormFile("file")
+		if err != nil || r.Header.Get("X-Link-Id") != "link-1" {
+			http.Error(w, "bad upload", http.StatusBadRequest)
+			return
+		}
+		content := make([]byte, 64)
+		n, _ := upload.Read(content)
+		received = string(content[:n])
+	}))
+	defer server.Close()
+
+	workDir := t.TempDir()
+	os.WriteFile(filepath.Join(workDir, "loot.txt"), []byte("secret"), 0644)
+	fs, err := file.NewFileService(t.TempDir(), "", workDir, t.TempDir(), server.URL, log)
+	if err != nil {
+		t.Fatalf("Failed to create file service: %v", err)
+	}
+	uploads := fs.UploadFiles([]string{"loot.txt"}, "op-1", "link-1")
+	if len(uploads) != 1 || uploads[0].Error != "" {
+		t.Fatalf("Unexpected upload records: %+v", uploads)
+	}
+	if received != "secret" {
+		t.Errorf("Expected server to receive file content, got %q", received)
+	}
+}
@@ -0,0 +1,79 @@
+package knowledge_test
+
+import (
+	"calderat/secondclass"
+	"calderat/service/knowledge"
+	"calderat/utils/logger"
+	"testing"
+)
+
+func TestAddFact(t *testing.T) {
+	log, _ := logger.New("DEBUG")
+	ks := knowledge.NewKnowledgeService(log)
+
+	stored, isNew := ks.AddFact(secondclass.NewFact("host.user.name", "root"))
+	if !isNew {
+		t.Fatal("Expected first fact to be new")
+	}
+	if stored.Origin != secondclass.IMPORTED || stored.Created.IsZero() {
+		t.Errorf("Expected defaults to be applied, got origin %q created %v", stored.Origin, stored.Created)
+	}
+
+	learned := secondclass.NewLearnedFact("host.user.name", "root", "link-1", "T1033")
+	merged, isNew := ks.AddFact(learned)
+	if isNew {
+		t.Error("Expected duplicate fact to be merged")
+	}
+	if merged != stored || len(merged.CollectedBy) != 1 || merged.CollectedBy[0] != "link-1" {
+		t.Errorf("Expected collecting link to be merged into stored fact, got %+v", merged)
+	}
+
+	if facts := ks.GetFacts(knowledge.FactCriteria{CollectedBy: "link-1"}); len(facts) != 1 {
+		t.Errorf("Expected 1 fact collected by link-1, got %d", len(facts))
+	}
+}
+
+func TestAddRelationship(t *testing.T) {
+	log, _ := logger.New("DEBUG")
+	ks := knowledge.NewKnowledgeService(log)
+
+	relationship := secondclass.NewRelationship(secondclass.NewFact("host.user.name", "bob"), "has_home", secondclass.NewFact("host.user.home", "/home/bob"))
+	if !ks.AddRelationship(relationship) {
+		t.Fatal("Expected relationship to be new")
+	}
+	duplicate := secondclass.NewRelationship(secondclass.NewFact("host.user.name", "bob"), "has_home", secondclass.NewFact("host.user.home", "/home/bob"))
+	if ks.AddRelationship(duplicate) {
+		t.Error("Expected duplicate relationship to be rejected")
+	}
+
+	if facts := ks.GetFacts(knowledge.FactCriteria{Trait: "host.user.home"}); len(facts) != 1 {
+		t.Errorf("Expected relationship target to be stored as a fact, got %d facts", len(facts))
+	}
+	found := ks.GetRelationships(knowledge.RelationshipCriteria{Source: secondclass.NewFact("host.user.name", "bob"), Edge: "has_home"})
+	if len(found) != 1 || found[0].Target.Value != "/home/bob" {
+		t.Errorf("Unexpected relationships: %+v", found)
+	}
+}
+
+func TestReplaceFacts(t *testing.T) {
+	log, _ := logger.New("DEBUG")
+	ks := knowledge.NewKnowledgeService(log)
+	ks.AddFact(secondclass.NewFact("ip", "10.0.0.1"))
+	ks.AddFact(secondclass.NewFact("ip", "10.0.0.2"))
+	ks.AddFact(secondclass.NewFact("count", "3"))
+
+	combinations := ks.ReplaceFacts("ping #{ip} -c #{count} # #{ip}")
+	if len(combinations) != 2 {
+		t.Fatalf("Expected 2 combinations, got %d", len(combinations))
+	}
+	if combinations[0].Command != "ping 10.0.0.1 -c 3 # 10.0.0.1" {
+		t.Errorf("Unexpected command: %s", combinations[0].Command)
+	}
+	if len(combinations[0].Used) != 2 {
+		t.Errorf("Expected 2 used facts, got %d", len(combinations[0].Used))
+	}
+
+	if combinations := ks.ReplaceFacts("cat #{missing}"); len(combinations) != 0 {
+		t.Errorf("Expected no combination for missing fact, got %d", len(combinations))
+	}
+}
@@ -0,0 +1,124 @@
+package objects_test
+
+import (
+	"calderat/objects"
+	"calderat/secondclass"
+	"calderat/service/knowledge"
+	"calderat/utils/logger"
+	"testing"
+)
+
+func TestEligibleExecutors(t *testing.T) {
+	ability := objects.Ability{
+		Executors: []secondclass.Executor{
+			{Name: "sh", Platform: "linux", Command: "whoami"},
+			{Name: "python", Command: "import os"},
+			{Name: "bash", Platform: "linux,darwin", Command: "whoami"},
+			{Name: "sh", Platform: "darwin", Command: "id -un"},
+		},
+	}
+	cases := []struct {
+		platform   string
+		shells     []string
+		preference []string
+		expected   []string
+	}{
+		{"linux", []string{"sh", "bash", "python"}, nil, []string{"sh", "python", "bash"}},
+		{"linux", []string{"sh", "bash", "python"}, []string{"bash", "sh"}, []string{"bash", "sh", "python"}},
+		{"linux", []string{"sh", "python"}, []string{"bash"}, []string{"sh", "python"}},
+		{"linux", []string{"cmd"}, []string{"sh"}, []string{}},
+		{"windows", []string{"sh", "bash", "python"}, nil, []string{"python"}},
+		{"darwin", []string{"sh", "bash"}, nil, []string{"bash", "sh"}},
+	}
+	for _, c := range cases {
+		eligible := ability.EligibleExecutors(c.platform, c.shells, c.preference)
+		if len(eligible) != len(c.expected) {
+			t.Fatalf("Expected %d executors on %s for shells %v and preference %v, got %d", len(c.expected), c.platform, c.shells, c.preference, len(eligible))
+		}
+		for i, executor := range eligible {
+			if executor.Name != c.expected[i] {
+				t.Errorf("Expected executor %d on %s to be %s, got %s", i, c.platform, c.expected[i], executor.Name)
+			}
+		}
+	}
+}
+
+func TestPlatformAliases(t *testing.T) {
+	cases := map[string]string{
+		"linux":   "linux",
+		"Windows": "windows",
+		"win":     "windows",
+		"macos":   "darwin",
+		"osx":     "darwin",
+		"mac":     "darwin",
+		"darwin":  "darwin",
+	}
+	for alias, expected := range cases {
+		if platform := secondclass.NormalizePlatform(alias); platform != expected {
+			t.Errorf("Expected %s to normalize to %s, got %s", alias, expected, platform)
+		}
+	}
+	executor := secondclass.Executor{Name: "sh", Platform: "macos"}
+	if !executor.RunsOn("darwin") || executor.RunsOn("linux") {
+		t.Errorf("Expected macos executor to run on darwin only")
+	}
+}
+
+func TestCreateLinksStdin(t *testing.T) {
+	log, _ := logger.New("ERROR")
+	ks := knowledge.NewKnowledgeService(log)
+	ks.AddFact(secondclass.NewFact("user", "alice"))
+	ks.AddFact(secondclass.NewFact("password", "one"))
+	ks.AddFact(secondclass.NewFact("password", "two"))
+	ability := objects.Ability{
+		Name:             "Login",
+		KnowledgeService: ks,
+		Executors: []secondclass.Executor{
+			{Name: "sh", Command: "su #{user} -c id", Stdin: "#{password}\n"},
+		},
+	}
+	links, _ := ability.CreateLinks(log, "linux", []string{"sh"}, nil)
+	if len(links) != 2 {
+		t.Fatalf("Expected a link per password, got %d", len(links))
+	}
+	for i, password := range []string{"one", "two"} {
+		if links[i].Command != "su alice -c id" || links[i].Stdin != password+"\n" {
+			t.Errorf("Expected link %d to pipe %q into the command, got %q into %q", i, password, links[i].Stdin, links[i].Command)
+		}
+		if len(links[i].Used) != 2 {
+			t.Errorf("Expected link %d to use the user and password facts, got %d facts", i, len(links[i].Used))
+		}
+	}
+}
+
+func TestFallbackLink(t *testing.T) {
+	log, _ := logger.New("ERROR")
+	ks := knowledge.NewKnowledgeService(log)
+	first, _ := ks.AddFact(secondclass.NewFact("host.file.path", "/tmp/a"))
+	ks.AddFact(secondclass.NewFact("host.file.path", "/tmp/b"))
+	ability := objects.Ability{
+		Name:             "Stage",
+		KnowledgeService: ks,
+		Requirements: []secondclass.Requirement{
+			{Module: "not_exists", RelationshipMatch: []secondclass.RelationshipMatch{{Source: "host.file.staged"}}},
+		},
+		Executors: []secondclass.Executor{
+			{Name: "bash", Command: "touch #{host.file.path}", Cleanup: []string{"rm #{host.file.path}"}},
+			{Name: "sh", Command: "touch #{host.file.path}", Cleanup: []string{"rm -f #{host.file.path}"}},
+		},
+	}
+	link := secondclass.Link{Command: "touch /tmp/a", Used: []*secondclass.Fact{first}, ExecutorsTried: []string{"bash"}}
+
+	fallback, cleanupLinks := ability.FallbackLink(log, &link, "linux", []string{"bash", "sh"}, nil)
+	if fallback == nil || fallback.Executor.Name != "sh" || fallback.Command != "touch /tmp/a" {
+		t.Fatalf("Expected the sh fallback for /tmp/a, got %+v", fallback)
+	}
+	if len(cleanupLinks) != 1 || cleanupLinks[0].Command != "rm -f /tmp/a" {
+		t.Errorf("Expected cleanup of /tmp/a only, got %v", cleanupLinks)
+	}
+
+	ks.AddFact(secondclass.NewFact("host.file.staged", "/tmp/a"))
+	if fallback, _ := ability.FallbackLink(log, &link, "linux", []string{"bash", "sh"}, nil); fallback != nil {
+		t.Errorf("Expected no fallback once the requirement fails, got %q", fallback.Command)
+	}
+}
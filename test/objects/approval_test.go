@@ -0,0 +1,33 @@
+package objects_test
+
+import (
+	"calderat/objects"
+	"calderat/secondclass"
+	"io"
+	"strings"
+	"testing"
+)
+
+func TestConsoleApprover(t *testing.T) {
+	cases := []struct {
+		input    string
+		decision string
+		command  string
+	}{
+		{"a\n", secondclass.APPROVED, "whoami"},
+		{"skip\n", secondclass.SKIPPED, "whoami"},
+		{"what\nq\n", secondclass.QUIT, "whoami"},
+		{"e\n\ne\nid -un\n", secondclass.EDITED, "id -un"},
+		{"", secondclass.QUIT, "whoami"},
+	}
+	for _, c := range cases {
+		link := secondclass.Link{ProcedureName: "Identify active user", Command: "whoami", Executor: secondclass.Executor{Name: "sh"}}
+		approval := objects.NewConsoleApprover(strings.NewReader(c.input), io.Discard).Approve(&link)
+		if approval.Decision != c.decision {
+			t.Errorf("Expected decision %s for input %q, got %s", c.decision, c.input, approval.Decision)
+		}
+		if link.Command != c.command {
+			t.Errorf("Expected command %q for input %q, got %q", c.command, c.input, link.Command)
+		}
+	}
+}
@@ -0,0 +1,139 @@
+package objects_test
+
+import (
+	"calderat/objects"
+	"calderat/secondclass"
+	"calderat/service/knowledge"
+	"calderat/utils/logger"
+	"os"
+	"path/filepath"
+	"slices"
+	"testing"
+	"time"
+)
+
+const yamlConfig = `
+data_dir: engagements/acme
+planner: batch
+executors: [bash, sh]
+timeout: 90s
+jitter: 1s-3s
+profiles:
+  lab:
+    jitter: 0s
+    stream: false
+  customer-safe:
+    cleanup: defer
+    executors: [sh]
+    timeout: 30
+    deny:
+      tactics: [impact]
+      techniques: [T1059]
+`
+
+const jsonConfig = `{
+	"data_dir": "engagements/acme",
+	"output_limit": 4096,
+	"profiles": {
+		"customer-safe": {"autonomous": false, "allow": {"abilities": ["a1"]}, "cleanup_timeout": "2m"}
+	}
+}`
+
+func writeConfig(t *testing.T, name, content string) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), name)
+	if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+		t.Fatalf("Failed to write config: %v", err)
+	}
+	return path
+}
+
+func TestLoadOperationConfig(t *testing.T) {
+	path := writeConfig(t, "calderat.yml", yamlConfig)
+
+	config, err := objects.LoadOperationConfig(path, "")
+	if err != nil {
+		t.Fatalf("Expected the config to load, got %v", err)
+	}
+	if config.DataDir != "engagements/acme" || config.Planner != "batch" || !config.Stream || config.Cleanup != objects.CLEANUP_RUN {
+		t.Errorf("Expected the file settings over the defaults, got %+v", config)
+	}
+	if time.Duration(config.Timeout) != 90*time.Second || config.Jitter.Min != time.Second || config.Jitter.Max != 3*time.Second {
+		t.Errorf("Expected a 90s timeout and 1s-3s jitter, got %s and %s", config.Timeout, config.Jitter)
+	}
+	if config.AdversaryFile() != filepath.Join("engagements/acme", "adversary.yml") {
+		t.Errorf("Expected the adversary in the data directory, got %s", config.AdversaryFile())
+	}
+
+	lab, err := objects.LoadOperationConfig(path, "lab")
+	if err != nil {
+		t.Fatalf("Expected the lab profile to load, got %v", err)
+	}
+	if lab.Stream || !lab.Jitter.Defined() || lab.Jitter.Max != 0 || lab.Planner != "batch" {
+		t.Errorf("Expected the lab profile to disable streaming and jitter only, got %+v", lab)
+	}
+
+	safe, err := objects.LoadOperationConfig(path, "customer-safe")
+	if err != nil {
+		t.Fatalf("Expected the customer-safe profile to load, got %v", err)
+	}
+	if safe.Cleanup != objects.CLEANUP_DEFER || !slices.Equal(safe.Executors, []string{"sh"}) || time.Duration(safe.Timeout) != 30*time.Second {
+		t.Errorf("Expected the customer-safe profile settings, got %+v", safe)
+	}
+	if err := safe.Validate(); err != nil {
+		t.Errorf("Expected the profile to be valid, got %v", err)
+	}
+
+	if _, err := objects.LoadOperationConfig(path, "prod"); err == nil {
+		t.Errorf("Expected an error for an unknown profile")
+	}
+}
+
+func TestLoadOperationConfigJSON(t *testing.T) {
+	path := writeConfig(t, "calderat.json", jsonConfig)
+	config, err := objects.LoadOperationConfig(path, "customer-safe")
+	if err != nil {
+		t.Fatalf("Expected the config to load, got %v", err)
+	}
+	if config.OutputLimit != 4096 || config.Autonomous || time.Duration(config.CleanupTimeout) != 2*time.Minute {
+		t.Errorf("Expected the JSON settings and profile, got %+v", config)
+	}
+	if !slices.Equal(config.Allow.Abilities, []string{"a1"}) || !config.Stream {
+		t.Errorf("Expected the allow list and default streaming, got %+v", config)
+	}
+}
+
+func TestDeniedAbilities(t *testing.T) {
+	log, err := logger.New("ERROR")
+	if err != nil {
+		t.Fatalf("Init log failed: %v", err)
+	}
+	ks := knowledge.NewKnowledgeService(log)
+	executors := []secondclass.Executor{{Name: "sh", Platform: "linux", Command: "id"}}
+	abilities := []objects.Ability{
+		{AbilityId: "wipe", Tactic: "impact", TechniqueId: "T1485", KnowledgeService: ks, Logger: log, Executors: executors},
+		{AbilityId: "script", Tactic: "execution", TechniqueId: "T1059.004", KnowledgeService: ks, Logger: log, Executors: executors},
+		{AbilityId: "whoami", Tactic: "discovery", TechniqueId: "T1033", KnowledgeService: ks, Logger: log, Executors: executors},
+	}
+	adversary := objects.Adversary{Name: "denied", AtomicOrdering: []string{"wipe", "script", "whoami"}, Logger: log}
+	config := testConfig(t)
+	config.Deny = objects.AbilityFilter{Tactics: []string{"Impact"}, Techniques: []string{"T1059"}}
+	operation := newOperation(t, config, adversary, abilities, log, ks)
+
+	_, available := operation.AvailableAbilities()
+	if len(available) != 1 || available[0].AbilityId != "whoami" {
+		t.Fatalf("Expected only whoami to be available, got %v", available)
+	}
+	for _, ignored := range operation.Ignored {
+		if ignored.Reason != objects.IGNORED_DENIED {
+			t.Errorf("Expected %s to be denied, got %s", ignored.AbilityId, ignored.Reason)
+		}
+	}
+
+	config.Deny = objects.AbilityFilter{}
+	config.Allow = objects.AbilityFilter{Abilities: []string{"script"}}
+	operation = newOperation(t, config, adversary, abilities, log, ks)
+	if _, available := operation.AvailableAbilities(); len(available) != 1 || available[0].AbilityId != "script" {
+		t.Errorf("Expected only the allowed ability to be available, got %v", available)
+	}
+}
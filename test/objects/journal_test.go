@@ -0,0 +1,145 @@
+package objects_test
+
+import (
+	"calderat/objects"
+	"calderat/secondclass"
+	"calderat/service/knowledge"
+	"calderat/utils/logger"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestReadJournal(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "journal.jsonl")
+	journal, err := objects.NewJournal(path)
+	if err != nil {
+		t.Fatalf("Failed to create journal: %v", err)
+	}
+	cleanup := secondclass.Link{LinkId: "cleanup-1", Command: "rm -f /tmp/x", IsCleanup: true}
+	entries := []objects.JournalEntry{
+		{Type: objects.JOURNAL_START, OperationId: "op", Adversary: "adversary"},
+		{Type: objects.JOURNAL_ABILITY, Index: 0, AbilityId: "first"},
+		{Type: objects.JOURNAL_CLEANUP, Link: &cleanup},
+		{Type: objects.JOURNAL_FACT, Fact: secondclass.NewFact("host.user.name", "alice")},
+		{Type: objects.JOURNAL_LINK, Link: &secondclass.Link{LinkId: "link-1", Command: "whoami", Status: secondclass.SUCCESS}},
+		{Type: objects.JOURNAL_ABILITY, Index: 1, AbilityId: "second"},
+	}
+	for _, entry := range entries {
+		if err := journal.Write(entry); err != nil {
+			t.Fatalf("Failed to write journal: %v", err)
+		}
+	}
+	journal.Close()
+	// A crash while writing leaves the last line cut short
+	file, _ := os.OpenFile(path, os.O_APPEND|os.O_WRONLY, 0600)
+	file.WriteString(`{"type":"link","link":{"link-id":"li`)
+	file.Close()
+
+	state, err := objects.ReadJournal(path)
+	if err != nil {
+		t.Fatalf("Failed to read journal: %v", err)
+	}
+	if state.OperationId != "op" || state.Adversary != "adversary" || state.Finished {
+		t.Errorf("Expected unfinished operation op of adversary, got %+v", state)
+	}
+	if state.Position != 1 || state.AbilityId != "second" {
+		t.Errorf("Expected position 1 at ability second, got %d at %s", state.Position, state.AbilityId)
+	}
+	if len(state.Links) != 1 || state.Links[0].LinkId != "link-1" || len(state.Facts) != 1 || len(state.CleanupLinks) != 1 {
+		t.Errorf("Expected one link, fact and cleanup link, got %d, %d and %d", len(state.Links), len(state.Facts), len(state.CleanupLinks))
+	}
+
+	journal, err = objects.OpenJournal(path)
+	if err != nil {
+		t.Fatalf("Failed to reopen journal: %v", err)
+	}
+	journal.Write(objects.JournalEntry{Type: objects.JOURNAL_CLEANED, Link: &cleanup})
+	journal.Write(objects.JournalEntry{Type: objects.JOURNAL_FINISH})
+	journal.Close()
+	state, err = objects.ReadJournal(path)
+	if err != nil {
+		t.Fatalf("Expected the cut line to be dropped on reopening, got: %v", err)
+	}
+	if !state.Finished || len(state.CleanupLinks) != 0 || len(state.Cleaned) != 1 {
+		t.Errorf("Expected a finished operation with its cleanup link run, got %+v", state)
+	}
+
+	os.WriteFile(path, []byte("{\"type\":\"start\",\"operation-id\":\"op\"}\n{broken\n{\"type\":\"finish\"}\n"), 0600)
+	if _, err := objects.ReadJournal(path); err == nil {
+		t.Error("Expected an error for a corrupt line followed by more entries")
+	}
+}
+
+func TestResumeInterrupted(t *testing.T) {
+	log, err := logger.New("ERROR")
+	if err != nil {
+		t.Fatalf("Init log failed: %v", err)
+	}
+	ks := knowledge.NewKnowledgeService(log)
+	abilities := []objects.Ability{
+		{AbilityId: "whoami", Name: "Whoami", KnowledgeService: ks, Logger: log, Executors: []secondclass.Executor{
+			{Name: "sh", Platform: "linux", Command: "whoami", Timeout: 5},
+		}},
+		{AbilityId: "stage", Name: "Stage", KnowledgeService: ks, Logger: log, Executors: []secondclass.Executor{
+			{Name: "sh", Platform: "linux", Command: "touch /tmp/staged", Cleanup: []string{"rm /tmp/staged"}, Timeout: 5},
+		}},
+	}
+	whoami, _ := abilities[0].CreateLinks(log, "linux", []string{"sh"}, nil)
+	stage, stageCleanup := abilities[1].CreateLinks(log, "linux", []string{"sh"}, nil)
+	whoami[0].Status = secondclass.SUCCESS
+	// Stopped while staging: the stage link was interrupted and the cleanup
+	// phase of the stop ran its cleanup
+	stage[0].Status = secondclass.INTERRUPTED
+	state := &objects.JournalState{
+		OperationId: "op",
+		Links:       []secondclass.Link{whoami[0], stage[0]},
+		Cleaned:     stageCleanup,
+	}
+
+	adversary := objects.Adversary{Name: "resume", AtomicOrdering: []string{"whoami", "stage"}, Logger: log}
+	operation := newOperation(t, testConfig(t), adversary, abilities, log, ks)
+	operation.Resume(state)
+	plan := operation.Plan()
+	if len(plan) != 2 || plan[0].Command != "touch /tmp/staged" || plan[1].Phase != objects.PHASE_CLEANUP || plan[1].Command != "rm /tmp/staged" {
+		t.Fatalf("Expected the interrupted link to run again with its cleanup, got %+v", plan)
+	}
+	if len(operation.Links) != 1 || operation.Links[0].ProcedureId != "whoami" {
+		t.Errorf("Expected only the finished link to be restored, got %d links", len(operation.Links))
+	}
+}
+
+func TestResumeLookAhead(t *testing.T) {
+	log, err := logger.New("ERROR")
+	if err != nil {
+		t.Fatalf("Init log failed: %v", err)
+	}
+	ks := knowledge.NewKnowledgeService(log)
+	abilities := []objects.Ability{
+		{AbilityId: "beacon", Name: "Beacon", Repeatable: true, KnowledgeService: ks, Logger: log, Executors: []secondclass.Executor{
+			{Name: "sh", Platform: "linux", Command: "echo beacon", Timeout: 5},
+		}},
+		{AbilityId: "whoami", Name: "Whoami", KnowledgeService: ks, Logger: log, Executors: []secondclass.Executor{
+			{Name: "sh", Platform: "linux", Command: "whoami", Timeout: 5},
+		}},
+	}
+	// The beacon finished before the crash, whoami did not run yet
+	beacon, _ := abilities[0].CreateLinks(log, "linux", []string{"sh"}, nil)
+	beacon[0].Status = secondclass.SUCCESS
+	state := &objects.JournalState{OperationId: "op", Links: beacon}
+
+	config := testConfig(t)
+	config.Planner = "look_ahead"
+	adversary := objects.Adversary{Name: "resume", AtomicOrdering: []string{"beacon", "whoami"}, Logger: log}
+	operation := newOperation(t, config, adversary, abilities, log, ks)
+	operation.Resume(state)
+	operation.Run()
+
+	runs := map[string]int{}
+	for _, link := range operation.Links {
+		runs[link.ProcedureId]++
+	}
+	if runs["beacon"] != 1 || runs["whoami"] != 1 {
+		t.Errorf("Expected the finished beacon not to run again and whoami to run, got %v", runs)
+	}
+}
@@ -0,0 +1,108 @@
+package objects_test
+
+import (
+	"calderat/objects"
+	"calderat/secondclass"
+	"calderat/service/knowledge"
+	"calderat/utils/logger"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestLoadMultipleAbilityPrehook(t *testing.T) {
+	log, err := logger.New("QUIET")
+	if err != nil {
+		t.Fatalf("Init log failed: %v", err)
+	}
+	path := filepath.Join(t.TempDir(), "abilities.yml")
+	os.WriteFile(path, []byte(`
+- id: named
+  name: Named
+  tactic: Discovery
+- id: nameless
+  tactic: discovery
+- name: No id
+`), 0644)
+
+	abilities, err := objects.LoadMultipleAbilityFromYAML(path, log, knowledge.NewKnowledgeService(log))
+	if err == nil || !strings.Contains(err.Error(), "ability 2") {
+		t.Errorf("Expected an error for the ability without a name, got %v", err)
+	}
+	if len(abilities) != 2 {
+		t.Fatalf("Expected the two named abilities, got %d", len(abilities))
+	}
+	if abilities[0].Tactic != "discovery" || abilities[1].Tactic != objects.DefaultTactic || abilities[1].AbilityId == "" {
+		t.Errorf("Expected the prehook defaults, got %+v and %+v", abilities[0], abilities[1])
+	}
+}
+
+func TestLoadAbilitiesFromDir(t *testing.T) {
+	log, err := logger.New("QUIET")
+	if err != nil {
+		t.Fatalf("Init log failed: %v", err)
+	}
+	dir := t.TempDir()
+	os.WriteFile(filepath.Join(dir, "a.yml"), []byte("- id: whoami\n  name: Whoami\n"), 0644)
+	os.WriteFile(filepath.Join(dir, "b.yml"), []byte("- id: whoami\n  name: Whoami again\n- id: nameless\n"), 0644)
+	os.WriteFile(filepath.Join(dir, "c.yml"), []byte("not: [a list"), 0644)
+	ks := knowledge.NewKnowledgeService(log)
+
+	abilities, err := objects.LoadAbilitiesFromDir(dir, log, ks)
+	if len(abilities) != 1 || abilities[0].Name != "Whoami" || abilities[0].File != filepath.Join(dir, "a.yml") {
+		t.Fatalf("Expected the first whoami only, got %+v", abilities)
+	}
+	if err == nil || len(objects.LoadErrors(err)) != 3 {
+		t.Errorf("Expected the duplicate, the nameless ability and the broken file, got %v", err)
+	}
+
+	if abilities, err := objects.LoadRunnableAbilities(dir, log, ks); err != nil || len(abilities) != 1 {
+		t.Errorf("Expected a run to skip the abilities that failed to load, got %d abilities and %v", len(abilities), err)
+	}
+	if _, err := objects.LoadRunnableAbilities(filepath.Join(dir, "missing"), log, ks); err == nil {
+		t.Errorf("Expected an error when no ability loads")
+	}
+}
+
+func TestLintAbility(t *testing.T) {
+	ability := objects.Ability{
+		AbilityId:   "broken",
+		TechniqueId: "T1059.01",
+		Executors: []secondclass.Executor{
+			{Name: "fish", Platform: "linux", Command: "cat #{host.file.path} #{remote.ip}", Timeout: 10},
+			{Name: "sh", Platform: "amiga", Command: "id", Cleanup: []string{"rm #{host.dir}"}},
+			{Name: "python", Code: "print(1)", Language: "cobol", Timeout: 10},
+		},
+	}
+	source := objects.Source{Facts: []secondclass.Fact{{Trait: "remote.ip", Value: "10.0.0.1"}}}
+	parsing := objects.Ability{Executors: []secondclass.Executor{{Parsers: []secondclass.Parser{
+		{Module: "line", ParserConfigs: []secondclass.ParserConfig{{Source: "host.dir"}}},
+	}}}}
+	known := objects.KnownTraits([]objects.Source{source}, []objects.Ability{parsing})
+
+	problems := objects.LintAbility(ability, known, 0)
+	expected := []string{
+		`technique_id "T1059.01"`,
+		"executor 1 (fish): unknown executor",
+		"executor 1 (fish): no source or parser provides a fact for #{host.file.path}",
+		`executor 2 (sh): unknown platform "amiga"`,
+		"executor 2 (sh): timeout 0",
+		`executor 3 (python): unknown code language "cobol"`,
+	}
+	if len(problems) != len(expected) {
+		t.Fatalf("Expected %d problems, got %d: %v", len(expected), len(problems), problems)
+	}
+	for i, problem := range problems {
+		if !strings.HasPrefix(problem, expected[i]) {
+			t.Errorf("Expected problem %d to start with %q, got %q", i, expected[i], problem)
+		}
+	}
+
+	for _, problem := range objects.LintAbility(ability, known, time.Minute) {
+		if strings.Contains(problem, "timeout") {
+			t.Errorf("Expected the default timeout to cover executors without one, got %q", problem)
+		}
+	}
+}
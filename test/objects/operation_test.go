@@ -0,0 +1,143 @@
+package objects_test
+
+import (
+	"calderat/objects"
+	"calderat/secondclass"
+	"calderat/service/knowledge"
+	"calderat/utils/logger"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// testConfig returns the default settings with the data, output and work
+// directories in temporary directories, without jitter or streaming.
+func testConfig(t *testing.T) *objects.OperationConfig {
+	config := objects.DefaultOperationConfig()
+	config.DataDir = t.TempDir()
+	config.OutputDir = t.TempDir()
+	config.WorkDir = t.TempDir()
+	config.Stream = false
+	config.Jitter.Set("0s")
+	return config
+}
+
+var linuxAgent = objects.Agent{OS: "linux", Shells: []string{"sh"}, IP: "127.0.0.1"}
+
+func newOperation(t *testing.T, config *objects.OperationConfig, adversary objects.Adversary, abilities []objects.Ability, log *logger.Logger, ks *knowledge.KnowledgeService) *objects.Operation {
+	t.Helper()
+	operation, err := objects.NewOperation(config, adversary, abilities, linuxAgent, log, ks)
+	if err != nil {
+		t.Fatalf("Failed to build operation: %v", err)
+	}
+	return operation
+}
+
+func TestIgnoredAbilities(t *testing.T) {
+	log, err := logger.New("ERROR")
+	if err != nil {
+		t.Fatalf("Init log failed: %v", err)
+	}
+	ks := knowledge.NewKnowledgeService(log)
+	abilities := []objects.Ability{
+		{AbilityId: "mac-only", Name: "Mac only", KnowledgeService: ks, Logger: log,
+			Executors: []secondclass.Executor{{Name: "sh", Platform: "darwin", Command: "sw_vers"}}},
+		{AbilityId: "cmd-only", Name: "Cmd only", KnowledgeService: ks, Logger: log,
+			Executors: []secondclass.Executor{{Name: "cmd", Platform: "linux", Command: "ver"}}},
+		{AbilityId: "elevated", Name: "Elevated", Privilege: "Elevated", KnowledgeService: ks, Logger: log,
+			Executors: []secondclass.Executor{{Name: "sh", Platform: "linux", Command: "id"}}},
+		{AbilityId: "runnable", Name: "Runnable", KnowledgeService: ks, Logger: log,
+			Executors: []secondclass.Executor{{Name: "sh", Platform: "linux", Command: "id"}}},
+	}
+	adversary := objects.Adversary{
+		Name:           "ignored",
+		AtomicOrdering: []string{"typo", "mac-only", "cmd-only", "elevated", "runnable"},
+		Logger:         log,
+	}
+	operation := newOperation(t, testConfig(t), adversary, abilities, log, ks)
+
+	_, available := operation.AvailableAbilities()
+	if len(available) != 1 || available[0].AbilityId != "runnable" {
+		t.Fatalf("Expected only the runnable ability to be available, got %v", available)
+	}
+	expected := map[string]string{
+		"typo":     objects.IGNORED_UNKNOWN_ID,
+		"mac-only": objects.IGNORED_PLATFORM,
+		"cmd-only": objects.IGNORED_NO_EXECUTOR,
+		"elevated": objects.IGNORED_PRIVILEGE,
+	}
+	if len(operation.Ignored) != len(expected) {
+		t.Fatalf("Expected %d ignored abilities, got %v", len(expected), operation.Ignored)
+	}
+	for _, ignored := range operation.Ignored {
+		if expected[ignored.AbilityId] != ignored.Reason {
+			t.Errorf("Expected ability %s to be ignored for %s, got %s", ignored.AbilityId, expected[ignored.AbilityId], ignored.Reason)
+		}
+	}
+
+	operation.AvailableAbilities()
+	if len(operation.Ignored) != len(expected) {
+		t.Errorf("Expected ignored abilities to be recorded once, got %d", len(operation.Ignored))
+	}
+}
+
+func TestLoadSources(t *testing.T) {
+	log, err := logger.New("ERROR")
+	if err != nil {
+		t.Fatalf("Init log failed: %v", err)
+	}
+	dir := t.TempDir()
+	first := filepath.Join(dir, "first.yml")
+	second := filepath.Join(dir, "second.yml")
+	os.WriteFile(first, []byte("id: first\nname: First\nfacts:\n- trait: host.user.name\n  value: alice\n"), 0644)
+	os.WriteFile(second, []byte("id: second\nfacts:\n- trait: host.user.name\n  value: bob\n- trait: remote.host.ip\n  value: 10.0.0.1\n"), 0644)
+
+	ks := knowledge.NewKnowledgeService(log)
+	adversary := objects.Adversary{Name: "sources", Logger: log}
+	operation := newOperation(t, testConfig(t), adversary, nil, log, ks)
+	if err := operation.LoadSources([]string{first, second}); err != nil {
+		t.Fatalf("Expected sources to load, got %v", err)
+	}
+	if operation.Source.Id != "first" {
+		t.Errorf("Expected the first source to be the operation source, got %q", operation.Source.Id)
+	}
+	users := ks.GetFacts(knowledge.FactCriteria{Trait: "host.user.name"})
+	ips := ks.GetFacts(knowledge.FactCriteria{Trait: "remote.host.ip"})
+	if len(users) != 2 || len(ips) != 1 || ips[0].Source != "second" {
+		t.Errorf("Expected the facts of both sources, got %v", ks.Facts())
+	}
+
+	if err := operation.LoadSources([]string{filepath.Join(dir, "missing.yml")}); err == nil {
+		t.Errorf("Expected an error for a missing source")
+	}
+}
+
+func TestSavePendingCleanup(t *testing.T) {
+	log, err := logger.New("ERROR")
+	if err != nil {
+		t.Fatalf("Init log failed: %v", err)
+	}
+	ks := knowledge.NewKnowledgeService(log)
+	abilities := []objects.Ability{
+		{AbilityId: "first", KnowledgeService: ks, Logger: log, Executors: []secondclass.Executor{
+			{Name: "sh", Platform: "linux", Command: "echo first", Cleanup: []string{"echo undo first"}, Timeout: 5},
+		}},
+		{AbilityId: "second", KnowledgeService: ks, Logger: log, Executors: []secondclass.Executor{
+			{Name: "sh", Platform: "linux", Command: "echo second", Cleanup: []string{"echo undo second"}, Timeout: 5},
+		}},
+	}
+	adversary := objects.Adversary{Name: "pending", AtomicOrdering: []string{"first", "second"}, Logger: log}
+	config := testConfig(t)
+	config.Cleanup = objects.CLEANUP_DEFER
+	operation := newOperation(t, config, adversary, abilities, log, ks)
+	operation.Run()
+
+	pending, err := secondclass.LoadCleanupLinksFromJson(operation.SavePendingCleanup(), log)
+	if err != nil || len(pending) != 2 || pending[0].Command != "echo undo first" {
+		t.Fatalf("Expected both cleanup links to be pending, got %v (%v)", pending, err)
+	}
+	operation.CleanupOperation()
+	if pending, err := secondclass.LoadCleanupLinksFromJson(operation.SavePendingCleanup(), log); err != nil || len(pending) != 0 {
+		t.Errorf("Expected no pending cleanup link after cleanup, got %v (%v)", pending, err)
+	}
+}
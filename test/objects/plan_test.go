@@ -0,0 +1,81 @@
+package objects_test
+
+import (
+	"calderat/objects"
+	"calderat/secondclass"
+	"calderat/service/knowledge"
+	"calderat/utils/logger"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestPlan(t *testing.T) {
+	log, err := logger.New("ERROR")
+	if err != nil {
+		t.Fatalf("Init log failed: %v", err)
+	}
+	dir := t.TempDir()
+	source := filepath.Join(dir, "source.yml")
+	os.WriteFile(source, []byte("id: files\nfacts:\n- trait: host.file.path\n  value: /tmp/secret\n"), 0644)
+	marker := filepath.Join(dir, "marker")
+
+	ks := knowledge.NewKnowledgeService(log)
+	abilities := []objects.Ability{
+		{AbilityId: "touch", Name: "Touch", Tactic: "execution", TechniqueId: "T1059.004", KnowledgeService: ks, Logger: log, Executors: []secondclass.Executor{
+			{Name: "sh", Platform: "linux", Command: "touch " + marker, Cleanup: []string{"rm " + marker}, Timeout: 5},
+		}},
+		{AbilityId: "read", Name: "Read", Tactic: "collection", TechniqueId: "T1005", KnowledgeService: ks, Logger: log, Executors: []secondclass.Executor{
+			{Name: "sh", Platform: "linux", Command: "cat #{host.file.path}", Cleanup: []string{"shred #{host.file.path}"}},
+		}},
+		{AbilityId: "exfil", Name: "Exfil", Tactic: "exfiltration", TechniqueId: "T1041", KnowledgeService: ks, Logger: log, Executors: []secondclass.Executor{
+			{Name: "sh", Platform: "linux", Command: "curl #{remote.host.url}", Timeout: 5},
+		}},
+	}
+	adversary := objects.Adversary{Name: "plan", AtomicOrdering: []string{"touch", "read", "exfil"}, Logger: log}
+	config := testConfig(t)
+	config.OutputDir = filepath.Join(dir, "out")
+	config.WorkDir = filepath.Join(dir, "work")
+	config.Timeout = objects.Duration(time.Minute)
+	operation := newOperation(t, config, adversary, abilities, log, ks)
+	if err := operation.LoadSources([]string{source}); err != nil {
+		t.Fatalf("Expected the source to load, got %v", err)
+	}
+
+	plan := operation.Plan()
+	expected := []objects.PlannedLink{
+		{Order: 1, Phase: objects.PHASE_EXPLOIT, AbilityId: "touch", Command: "touch " + marker, Timeout: objects.Duration(5 * time.Second)},
+		{Order: 2, Phase: objects.PHASE_EXPLOIT, AbilityId: "read", Command: "cat /tmp/secret", Timeout: objects.Duration(time.Minute)},
+		{Order: 3, Phase: objects.PHASE_CLEANUP, AbilityId: "read", Command: "shred /tmp/secret", Timeout: objects.Duration(time.Minute)},
+		{Order: 4, Phase: objects.PHASE_CLEANUP, AbilityId: "touch", Command: "rm " + marker, Timeout: objects.Duration(5 * time.Second)},
+	}
+	if len(plan) != len(expected) {
+		t.Fatalf("Expected %d planned links, got %d: %+v", len(expected), len(plan), plan)
+	}
+	for i, planned := range plan {
+		want := expected[i]
+		if planned.Order != want.Order || planned.Phase != want.Phase || planned.AbilityId != want.AbilityId ||
+			planned.Command != want.Command || planned.Timeout != want.Timeout || planned.Executor != "sh" {
+			t.Errorf("Expected planned link %d to be %+v, got %+v", i+1, want, planned)
+		}
+	}
+	if len(plan[1].Facts) != 1 || plan[1].Facts[0] != "host.file.path=/tmp/secret" {
+		t.Errorf("Expected the source fact to be listed, got %v", plan[1].Facts)
+	}
+	if len(operation.Ignored) != 1 || operation.Ignored[0].AbilityId != "exfil" || operation.Ignored[0].Reason != objects.IGNORED_UNMET_FACT {
+		t.Errorf("Expected only exfil to be ignored for an unmet fact, got %+v", operation.Ignored)
+	}
+	if len(operation.Links) != 0 {
+		t.Errorf("Expected no link to run, got %d", len(operation.Links))
+	}
+	if _, err := os.Stat(marker); !os.IsNotExist(err) {
+		t.Errorf("Expected the planned command not to run")
+	}
+	if _, err := os.Stat(operation.OutputDir); !os.IsNotExist(err) {
+		t.Errorf("Expected nothing to be written to the output directory")
+	}
+	if _, err := os.Stat(config.WorkDir); !os.IsNotExist(err) {
+		t.Errorf("Expected the working directory not to be created")
+	}
+}
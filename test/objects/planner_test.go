@@ -0,0 +1,152 @@
+package objects_test
+
+import (
+	"calderat/objects"
+	"calderat/secondclass"
+	"calderat/service/knowledge"
+	"calderat/utils/logger"
+	"slices"
+	"testing"
+)
+
+func TestNewPlanner(t *testing.T) {
+	cases := map[string]string{
+		"":           "atomic",
+		"atomic":     "atomic",
+		"batch":      "batch",
+		"buckets":    "buckets",
+		"look_ahead": "look_ahead",
+		"look-ahead": "look_ahead",
+	}
+	for name, expected := range cases {
+		planner, err := objects.NewPlanner(name)
+		if err != nil {
+			t.Fatalf("Expected planner for %q, got error: %v", name, err)
+		}
+		if planner.Name() != expected {
+			t.Errorf("Expected planner %q to resolve to %s, got %s", name, expected, planner.Name())
+		}
+	}
+	if _, err := objects.NewPlanner("random"); err == nil {
+		t.Error("Expected error for unknown planner, got nil")
+	}
+}
+
+// runPlanner runs the abilities in order with a planner and returns the ids of
+// the abilities of the links it ran, in order.
+func runPlanner(t *testing.T, name string, abilities []objects.Ability) []string {
+	t.Helper()
+	log, err := logger.New("ERROR")
+	if err != nil {
+		t.Fatalf("Init log failed: %v", err)
+	}
+	ks := knowledge.NewKnowledgeService(log)
+	ordering := []string{}
+	for i := range abilities {
+		abilities[i].KnowledgeService = ks
+		abilities[i].Logger = log
+		ordering = append(ordering, abilities[i].AbilityId)
+	}
+	adversary := objects.Adversary{Name: name, AtomicOrdering: ordering, Logger: log}
+	config := testConfig(t)
+	config.Planner = name
+	config.Cleanup = objects.CLEANUP_DEFER
+	operation := newOperation(t, config, adversary, abilities, log, ks)
+	operation.Run()
+
+	order := []string{}
+	for _, link := range operation.Links {
+		order = append(order, link.ProcedureId)
+	}
+	return order
+}
+
+func shExecutors(command string, parsers ...secondclass.Parser) []secondclass.Executor {
+	return []secondclass.Executor{{Name: "sh", Platform: "linux", Command: command, Timeout: 5, Parsers: parsers}}
+}
+
+var userParser = secondclass.Parser{Module: "line", ParserConfigs: []secondclass.ParserConfig{{Source: "host.user.name"}}}
+
+func TestBatchPlanner(t *testing.T) {
+	abilities := []objects.Ability{
+		{AbilityId: "greet", Tactic: "discovery", Executors: shExecutors("echo hello #{host.user.name}")},
+		{AbilityId: "users", Tactic: "discovery", Executors: shExecutors("echo alice", userParser)},
+		{AbilityId: "host", Tactic: "discovery", Executors: shExecutors("echo host")},
+	}
+	// greet waits for the user users finds in the first round
+	expected := []string{"users", "host", "greet"}
+	if order := runPlanner(t, "batch", abilities); !slices.Equal(order, expected) {
+		t.Errorf("Expected links %v, got %v", expected, order)
+	}
+	if order := runPlanner(t, "atomic", abilities); !slices.Equal(order, []string{"users", "host"}) {
+		t.Errorf("Expected the atomic planner to run greet before its fact exists, got %v", order)
+	}
+}
+
+func TestBucketsPlanner(t *testing.T) {
+	abilities := []objects.Ability{
+		{AbilityId: "wipe", Tactic: "impact", Executors: shExecutors("echo wipe")},
+		{AbilityId: "custom", Tactic: "discovery", Buckets: []string{"custom"}, Executors: shExecutors("echo custom")},
+		{AbilityId: "greet", Tactic: "discovery", Executors: shExecutors("echo hello #{host.user.name}")},
+		{AbilityId: "whoami", Tactic: "discovery", Executors: shExecutors("echo whoami")},
+		{AbilityId: "users", Tactic: "discovery", Executors: shExecutors("echo alice", userParser)},
+		{AbilityId: "shell", Tactic: "execution", Executors: shExecutors("echo shell")},
+	}
+	// Buckets run in phase order and custom buckets last. The discovery bucket
+	// runs in rounds until greet gets its user, before the impact bucket starts.
+	expected := []string{"shell", "whoami", "users", "greet", "wipe", "custom"}
+	if order := runPlanner(t, "buckets", abilities); !slices.Equal(order, expected) {
+		t.Errorf("Expected links %v, got %v", expected, order)
+	}
+}
+
+func TestLookAheadPlanner(t *testing.T) {
+	abilities := []objects.Ability{
+		{AbilityId: "host", Tactic: "discovery", Executors: shExecutors("echo host")},
+		{AbilityId: "greet", Tactic: "discovery", Executors: shExecutors("echo hello #{host.user.name}")},
+		{AbilityId: "users", Tactic: "discovery", Executors: shExecutors("echo alice", userParser)},
+	}
+	// users unlocks greet so its reward is the highest, then ties run in order
+	expected := []string{"users", "host", "greet"}
+	if order := runPlanner(t, "look_ahead", abilities); !slices.Equal(order, expected) {
+		t.Errorf("Expected links %v, got %v", expected, order)
+	}
+}
+
+func TestBatchPlannerStdinVariants(t *testing.T) {
+	passwordParser := secondclass.Parser{Module: "line", ParserConfigs: []secondclass.ParserConfig{{Source: "user.password"}}}
+	login := shExecutors("cat")
+	login[0].Stdin = "#{user.password}"
+	abilities := []objects.Ability{
+		{AbilityId: "login", Tactic: "credential-access", Executors: login},
+		{AbilityId: "reuse", Tactic: "credential-access", Executors: shExecutors("echo #{host.user.name}-2", passwordParser)},
+		{AbilityId: "users", Tactic: "discovery", Executors: shExecutors("echo alice", userParser)},
+		{AbilityId: "passwords", Tactic: "credential-access", Executors: shExecutors("echo one", passwordParser)},
+	}
+	// The second password is learned a round after the first, and login runs
+	// the same command for both, only the stdin differs
+	expected := []string{"users", "passwords", "login", "reuse", "login"}
+	if order := runPlanner(t, "batch", abilities); !slices.Equal(order, expected) {
+		t.Errorf("Expected links %v, got %v", expected, order)
+	}
+}
+
+func TestRepeatableAbility(t *testing.T) {
+	abilities := []objects.Ability{
+		{AbilityId: "beacon", Tactic: "discovery", Repeatable: true, Executors: shExecutors("echo beacon")},
+		{AbilityId: "greet", Tactic: "discovery", Executors: shExecutors("echo hello #{host.user.name}")},
+		{AbilityId: "users", Tactic: "discovery", Executors: shExecutors("echo alice", userParser)},
+	}
+	// The beacon runs again in every round but does not keep the rounds going:
+	// the second round runs greet, the third finds no new link
+	expected := map[string][]string{
+		"batch":      {"beacon", "users", "beacon", "greet", "beacon"},
+		"buckets":    {"beacon", "users", "beacon", "greet", "beacon"},
+		"look_ahead": {"users", "beacon", "greet"},
+	}
+	for planner, order := range expected {
+		if got := runPlanner(t, planner, abilities); !slices.Equal(got, order) {
+			t.Errorf("Expected the %s planner to run %v, got %v", planner, order, got)
+		}
+	}
+}
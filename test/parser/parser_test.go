@@ -0,0 +1,203 @@
+package parser_test
+
+import (
+	"calderat/secondclass"
+	"calderat/service/parser"
+	"calderat/utils/logger"
+	"testing"
+)
+
+func TestNewParsingService(t *testing.T) {
+	log, _ := logger.New("DEBUG")
+	cases := map[string]string{
+		"regex":                               "regex",
+		"line":                                "line",
+		"plugins.stockpile.app.parsers.basic": "line",
+		"json":                                "json",
+		"keyvalue":                            "keyvalue",
+	}
+	for module, expected := range cases {
+		ps, err := parser.NewParsingService(module, log)
+		if err != nil {
+			t.Fatalf("Expected parser for module %s, got error: %v", module, err)
+		}
+		if ps.ShortName() != expected {
+			t.Errorf("Expected module %s to resolve to %s, got %s", module, expected, ps.ShortName())
+		}
+	}
+	if _, err := parser.NewParsingService("unknown", log); err == nil {
+		t.Error("Expected error for unknown parser module, got nil")
+	}
+}
+
+func TestParseOutput(t *testing.T) {
+	log, _ := logger.New("DEBUG")
+	parsers := []secondclass.Parser{
+		{
+			Module: "line",
+			ParserConfigs: []secondclass.ParserConfig{
+				{Source: "host.user.name"},
+			},
+		},
+	}
+
+	relationships := parser.ParseOutput("root\n\nadmin\n", parsers, log)
+	if len(relationships) != 2 {
+		t.Fatalf("Expected 2 relationships, got %d", len(relationships))
+	}
+	if relationships[1].Source.Trait != "host.user.name" || relationships[1].Source.Value != "admin" {
+		t.Errorf("Unexpected fact: %s=%s", relationships[1].Source.Trait, relationships[1].Source.Value)
+	}
+}
+
+func TestRegexParse(t *testing.T) {
+	log, _ := logger.New("DEBUG")
+	config := secondclass.ParserConfig{
+		Source: "host.user.name",
+		Edge:   "has_home",
+		Target: "host.user.home",
+		CustomParserVals: map[string]string{
+			"regex": `(?m)^(?P<source>[^:]+):[^:]*:[^:]*:[^:]*:[^:]*:(?P<target>[^:]*):`,
+		},
+	}
+
+	relationships, err := parser.NewRegex(log).Parse("root:x:0:0:root:/root:/bin/bash\nbob:x:1000:1000::/home/bob:/bin/sh\n", config)
+	if err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+	if len(relationships) != 2 {
+		t.Fatalf("Expected 2 relationships, got %d", len(relationships))
+	}
+	if relationships[1].Source.Value != "bob" || relationships[1].Target == nil || relationships[1].Target.Value != "/home/bob" {
+		t.Errorf("Unexpected relationship: %+v", relationships[1])
+	}
+	if relationships[1].Edge != "has_home" {
+		t.Errorf("Expected edge has_home, got %s", relationships[1].Edge)
+	}
+}
+
+func TestRegexParseTargetGroupOnly(t *testing.T) {
+	log, _ := logger.New("DEBUG")
+	config := secondclass.ParserConfig{
+		Source: "host.user.name",
+		Edge:   "has_home",
+		Target: "host.user.home",
+		CustomParserVals: map[string]string{
+			"regex": `(?m)^\w+ home (?P<target>\S+)$`,
+		},
+	}
+
+	relationships, err := parser.NewRegex(log).Parse("bob home /home/bob\n", config)
+	if err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+	if len(relationships) != 1 {
+		t.Fatalf("Expected 1 relationship, got %d", len(relationships))
+	}
+	if relationships[0].Source.Value != "bob home /home/bob" || relationships[0].Target == nil || relationships[0].Target.Value != "/home/bob" {
+		t.Errorf("Expected the whole match as source and the target group as target, got %+v", relationships[0])
+	}
+}
+
+func TestJsonParse(t *testing.T) {
+	log, _ := logger.New("DEBUG")
+	config := secondclass.ParserConfig{
+		Source: "host.user.name",
+		Edge:   "has_id",
+		Target: "host.user.id",
+		CustomParserVals: map[string]string{
+			"path":        "users.*.name",
+			"target_path": "users[].uid",
+		},
+	}
+
+	relationships, err := parser.NewJson(log).Parse(`{"users": [{"name": "root", "uid": 0}, {"name": "bob", "uid": 1000}]}`, config)
+	if err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+	if len(relationships) != 2 {
+		t.Fatalf("Expected 2 relationships, got %d", len(relationships))
+	}
+	if relationships[1].Source.Value != "bob" || relationships[1].Target.Value != "1000" {
+		t.Errorf("Unexpected relationship: %s -> %s", relationships[1].Source.Value, relationships[1].Target.Value)
+	}
+
+	if _, err := parser.NewJson(log).Parse("not json", config); err == nil {
+		t.Error("Expected error for invalid JSON output, got nil")
+	}
+}
+
+func TestJsonParseMissingField(t *testing.T) {
+	log, _ := logger.New("DEBUG")
+	config := secondclass.ParserConfig{
+		Source: "host.user.name",
+		Edge:   "has_id",
+		Target: "host.user.id",
+		CustomParserVals: map[string]string{
+			"path":        "users[].name",
+			"target_path": "users[].uid",
+		},
+	}
+
+	relationships, err := parser.NewJson(log).Parse(`{"users": [{"name": "root", "uid": 0}, {"name": "nobody"}, {"uid": 7}, {"name": "bob", "uid": 1000}]}`, config)
+	if err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+	if len(relationships) != 3 {
+		t.Fatalf("Expected 3 relationships, got %d", len(relationships))
+	}
+	if relationships[1].Source.Value != "nobody" || relationships[1].Target != nil {
+		t.Errorf("Expected nobody without a target, got %+v", relationships[1])
+	}
+	if relationships[2].Source.Value != "bob" || relationships[2].Target == nil || relationships[2].Target.Value != "1000" {
+		t.Errorf("Expected bob -> 1000, got %+v", relationships[2])
+	}
+}
+
+func TestJsonParseObjectWildcard(t *testing.T) {
+	log, _ := logger.New("DEBUG")
+	config := secondclass.ParserConfig{
+		Source: "remote.host.name",
+		Edge:   "has_ip",
+		Target: "remote.host.ip",
+		CustomParserVals: map[string]string{
+			"path":        "hosts.*.name",
+			"target_path": "hosts.*.ip",
+		},
+	}
+	output := `{"hosts": {"c": {"name": "gamma", "ip": "10.0.0.3"}, "a": {"name": "alpha", "ip": "10.0.0.1"}, "b": {"name": "beta", "ip": "10.0.0.2"}}}`
+
+	for run := 0; run < 10; run++ {
+		relationships, err := parser.NewJson(log).Parse(output, config)
+		if err != nil {
+			t.Fatalf("Expected no error, got: %v", err)
+		}
+		expected := [][2]string{{"alpha", "10.0.0.1"}, {"beta", "10.0.0.2"}, {"gamma", "10.0.0.3"}}
+		if len(relationships) != len(expected) {
+			t.Fatalf("Expected %d relationships, got %d", len(expected), len(relationships))
+		}
+		for i, relationship := range relationships {
+			if relationship.Source.Value != expected[i][0] || relationship.Target == nil || relationship.Target.Value != expected[i][1] {
+				t.Fatalf("Expected %s -> %s at %d, got %+v", expected[i][0], expected[i][1], i, relationship)
+			}
+		}
+	}
+}
+
+func TestKeyValueParse(t *testing.T) {
+	log, _ := logger.New("DEBUG")
+	config := secondclass.ParserConfig{
+		Source: "host.os.name",
+		CustomParserVals: map[string]string{
+			"key": "NAME",
+		},
+	}
+
+	relationships, err := parser.NewKeyValue(log).Parse("NAME=\"Ubuntu\"\nVERSION_ID=\"22.04\"\n", config)
+	if err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+	if len(relationships) != 1 || relationships[0].Source.Value != "Ubuntu" {
+		t.Errorf("Unexpected relationships: %+v", relationships)
+	}
+}
@@ -0,0 +1,85 @@
+package requirement_test
+
+import (
+	"calderat/secondclass"
+	"calderat/service/knowledge"
+	"calderat/service/requirement"
+	"calderat/utils/logger"
+	"testing"
+)
+
+func newKnowledge() *knowledge.KnowledgeService {
+	log, _ := logger.New("DEBUG")
+	knowledgeService := knowledge.NewKnowledgeService(log)
+	knowledgeService.AddFact(secondclass.NewFact("ip", "10.0.0.2"))
+	knowledgeService.AddFact(secondclass.NewFact("count", "2"))
+	knowledgeService.AddRelationship(secondclass.NewRelationship(secondclass.NewFact("ip", "10.0.0.1"), "has_count", secondclass.NewFact("count", "1")))
+	return knowledgeService
+}
+
+func TestPairedRequirement(t *testing.T) {
+	log, _ := logger.New("DEBUG")
+	knowledge := newKnowledge()
+	requirements := []secondclass.Requirement{
+		*secondclass.NewRequirement("plugins.stockpile.app.requirements.paired", []secondclass.RelationshipMatch{
+			{Source: "ip", Edge: "has_count", Target: "count"},
+		}),
+	}
+
+	paired := []*secondclass.Fact{secondclass.NewFact("ip", "10.0.0.1"), secondclass.NewFact("count", "1")}
+	if !requirement.Enforce(requirements, paired, knowledge, log) {
+		t.Error("Expected paired facts to satisfy the requirement")
+	}
+
+	unpaired := []*secondclass.Fact{secondclass.NewFact("ip", "10.0.0.1"), secondclass.NewFact("count", "2")}
+	if requirement.Enforce(requirements, unpaired, knowledge, log) {
+		t.Error("Expected unpaired facts to fail the requirement")
+	}
+
+	sourceOnly := []*secondclass.Fact{secondclass.NewFact("ip", "10.0.0.1")}
+	if requirement.Enforce(requirements, sourceOnly, knowledge, log) {
+		t.Error("Expected link without target fact to fail the paired requirement")
+	}
+}
+
+func TestBasicRequirement(t *testing.T) {
+	log, _ := logger.New("DEBUG")
+	knowledge := newKnowledge()
+	match := secondclass.RelationshipMatch{Source: "ip", Edge: "has_count"}
+
+	if !requirement.NewBasic(log).Enforce(match, []*secondclass.Fact{secondclass.NewFact("ip", "10.0.0.1")}, knowledge) {
+		t.Error("Expected fact with edge to satisfy basic requirement")
+	}
+	if requirement.NewBasic(log).Enforce(match, []*secondclass.Fact{secondclass.NewFact("ip", "10.0.0.2")}, knowledge) {
+		t.Error("Expected fact without edge to fail basic requirement")
+	}
+}
+
+func TestExistentialAndNotExists(t *testing.T) {
+	log, _ := logger.New("DEBUG")
+	knowledge := newKnowledge()
+
+	if !requirement.NewExistential(log).Enforce(secondclass.RelationshipMatch{Source: "ip"}, nil, knowledge) {
+		t.Error("Expected existential requirement to pass when facts exist")
+	}
+	if !requirement.NewNotExists(log).Enforce(secondclass.RelationshipMatch{Source: "host.user.name"}, nil, knowledge) {
+		t.Error("Expected not_exists requirement to pass when no fact exists")
+	}
+
+	match := secondclass.RelationshipMatch{Source: "ip", Edge: "has_count"}
+	used := []*secondclass.Fact{secondclass.NewFact("ip", "10.0.0.2")}
+	if !requirement.NewNotExists(log).Enforce(match, used, knowledge) {
+		t.Error("Expected not_exists requirement to pass for fact without edge")
+	}
+	if requirement.NewExistential(log).Enforce(match, used, knowledge) {
+		t.Error("Expected existential requirement to fail for fact without edge")
+	}
+}
+
+func TestUnknownRequirementModule(t *testing.T) {
+	log, _ := logger.New("DEBUG")
+	requirements := []secondclass.Requirement{*secondclass.NewRequirement("unknown", nil)}
+	if requirement.Enforce(requirements, nil, newKnowledge(), log) {
+		t.Error("Expected unknown requirement module to fail closed")
+	}
+}
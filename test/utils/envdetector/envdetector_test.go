@@ -4,7 +4,9 @@ import (
 	"calderat/utils/envdetector"
 	"calderat/utils/logger"
 	"net"
+	"os"
 	"runtime"
+	"slices"
 	"testing"
 )
 
@@ -47,3 +49,59 @@ func TestGetAllIPAddresses(t *testing.T) {
 		}
 	}
 }
+
+// TestDetectLinuxShells ensures bash is reported on its own instead of being folded into sh
+func TestDetectLinuxShells(t *testing.T) {
+	if runtime.GOOS != "linux" {
+		t.Skip("Skipping test: Linux shells only")
+	}
+	log, _ := logger.New("DEBUG")
+	env, err := envdetector.DetectEnvironment(log)
+	if err != nil {
+		t.Fatalf("Failed to detect environment: %v", err)
+	}
+
+	if _, err := os.Stat("/bin/bash"); err == nil && !slices.Contains(env.ShortnameShells, "bash") {
+		t.Errorf("Expected bash in shells, got %v", env.ShortnameShells)
+	}
+	if _, err := os.Stat("/bin/sh"); err == nil && !slices.Contains(env.ShortnameShells, "sh") {
+		t.Errorf("Expected sh in shells, got %v", env.ShortnameShells)
+	}
+	if len(envdetector.RemoveDuplicates(env.ShortnameShells)) != len(env.ShortnameShells) {
+		t.Errorf("Expected no duplicate shells, got %v", env.ShortnameShells)
+	}
+}
+
+// TestParseCapabilities ensures effective capabilities are decoded from /proc/self/status
+func TestParseCapabilities(t *testing.T) {
+	status := "Name:\tcalderat\nCapInh:\t0000000000000000\nCapPrm:\t0000000000200001\nCapEff:\t0000000000200001\n"
+	capabilities, err := envdetector.ParseCapabilities(status)
+	if err != nil {
+		t.Fatalf("ParseCapabilities failed: %v", err)
+	}
+	expected := []string{"CAP_CHOWN", "CAP_SYS_ADMIN"}
+	if !slices.Equal(capabilities, expected) {
+		t.Errorf("Expected capabilities %v, got %v", expected, capabilities)
+	}
+	if _, err := envdetector.ParseCapabilities("Name:\tcalderat\n"); err == nil {
+		t.Error("Expected error without CapEff line, got nil")
+	}
+}
+
+// TestDetectPrivilege ensures the effective UID is reported on Unix systems
+func TestDetectPrivilege(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("UIDs do not apply on Windows")
+	}
+	log, _ := logger.New("DEBUG")
+	env, err := envdetector.DetectEnvironment(log)
+	if err != nil {
+		t.Fatalf("DetectEnvironment failed: %v", err)
+	}
+	if env.Privilege.EffectiveUID != os.Geteuid() {
+		t.Errorf("Expected effective UID %d, got %d", os.Geteuid(), env.Privilege.EffectiveUID)
+	}
+	if os.Geteuid() == 0 && !env.Privilege.Elevated {
+		t.Error("Expected root to be elevated")
+	}
+}
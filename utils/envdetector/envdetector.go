@@ -6,6 +6,7 @@ import (
 	"net"
 	"os"
 	"os/exec"
+	"path/filepath"
 	"runtime"
 	"strings"
 )
@@ -17,6 +18,7 @@ type Environment struct {
 	CurrentShell    string   // Current shell in use
 	AvailableShells []string // Available shells
 	ShortnameShells []string
+	Privilege       Privilege       // Whether the agent runs elevated
 	NetworkInfo     []NetworkDetail // Network interface details
 	Logger          *logger.Logger
 }
@@ -52,7 +54,10 @@ func DetectEnvironment(log *logger.Logger) (*Environment, error) {
 		return nil, err
 	}
 	env.AvailableShells = availableShells
-	env.ShortnameShells = extractShortnameShells(env.AvailableShells, env.OS)
+	env.ShortnameShells = RemoveDuplicates(extractShortnameShells(env.AvailableShells, env.OS))
+
+	// Detect privileges
+	env.Privilege = detectPrivilege()
 
 	// Detect network interfaces
 	networkInfo, err := detectNetworkInterfaces()
@@ -90,10 +95,12 @@ func detectCurrentShell() (string, error) {
 }
 
 // detectAvailableShells lists the available shells by checking common shell paths
+// and the interpreters found in PATH
 func detectAvailableShells() ([]string, error) {
 	shellPaths := []string{
 		"/bin/bash",                      // Bash
 		"/bin/zsh",                       // Zsh
+		"/usr/bin/zsh",                   // Zsh (distributions without /bin/zsh)
 		"/usr/bin/fish",                  // Fish shell
 		"/bin/sh",                        // Default shell
 		"/usr/bin/pwsh",                  // PowerShell Core
@@ -101,6 +108,11 @@ func detectAvailableShells() ([]string, error) {
 		"C:\\Windows\\System32\\cmd.exe", // Windows Command Prompt
 		"C:\\Windows\\System32\\WindowsPowerShell\\v1.0\\powershell.exe", // PowerShell
 	}
+	interpreters := []string{
+		"python3", // Python 3
+		"python",  // Python
+		"perl",    // Perl
+	}
 
 	var available []string
 	for _, path := range shellPaths {
@@ -108,20 +120,40 @@ func detectAvailableShells() ([]string, error) {
 			available = append(available, path)
 		}
 	}
+	for _, name := range interpreters {
+		if path, err := exec.LookPath(name); err == nil {
+			available = append(available, path)
+		}
+	}
 	return available, nil
 }
 
+// extractShortnameShells maps shell paths to the executor names used in abilities.
+// Only shells with an executing service are reported.
 func extractShortnameShells(shellPaths []string, os string) []string {
 	shortnames := []string{}
 	for _, path := range shellPaths {
-		if strings.Contains(strings.ToLower(path), "powershell") && os == "windows" {
-			shortnames = append(shortnames, "psh")
+		lowerPath := strings.ToLower(path)
+		if os == "windows" {
+			if strings.Contains(lowerPath, "powershell") {
+				shortnames = append(shortnames, "psh")
+			}
+			if strings.Contains(lowerPath, "cmd") {
+				shortnames = append(shortnames, "cmd")
+			}
+			continue
 		}
-		if strings.Contains(strings.ToLower(path), "cmd") && os == "windows" {
-			shortnames = append(shortnames, "cmd")
+		if os != "linux" {
+			continue
 		}
-		if strings.Contains(strings.ToLower(path), "sh") && os == "linux" {
-			shortnames = append(shortnames, "sh")
+		switch name := filepath.Base(lowerPath); {
+		case name == "sh", name == "bash", name == "zsh", name == "perl", name == "pwsh":
+			shortnames = append(shortnames, name)
+		case strings.HasPrefix(name, "python"):
+			shortnames = append(shortnames, "python")
+			if strings.HasPrefix(name, "python3") {
+				shortnames = append(shortnames, "python3")
+			}
 		}
 	}
 	return shortnames
@@ -0,0 +1,72 @@
+package envdetector
+
+import (
+	"bufio"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// Privilege holds the privileges the agent runs with
+type Privilege struct {
+	Elevated     bool     `json:"elevated"`
+	EffectiveUID int      `json:"euid"`                   // -1 where UIDs do not apply
+	Capabilities []string `json:"capabilities,omitempty"` // Effective Linux capabilities
+	Detail       string   `json:"detail"`                 // Why the agent is considered elevated or not
+}
+
+// capabilityNames are the Linux capability names indexed by their bit number
+var capabilityNames = []string{
+	"CAP_CHOWN", "CAP_DAC_OVERRIDE", "CAP_DAC_READ_SEARCH", "CAP_FOWNER", "CAP_FSETID",
+	"CAP_KILL", "CAP_SETGID", "CAP_SETUID", "CAP_SETPCAP", "CAP_LINUX_IMMUTABLE",
+	"CAP_NET_BIND_SERVICE", "CAP_NET_BROADCAST", "CAP_NET_ADMIN", "CAP_NET_RAW", "CAP_IPC_LOCK",
+	"CAP_IPC_OWNER", "CAP_SYS_MODULE", "CAP_SYS_RAWIO", "CAP_SYS_CHROOT", "CAP_SYS_PTRACE",
+	"CAP_SYS_PACCT", "CAP_SYS_ADMIN", "CAP_SYS_BOOT", "CAP_SYS_NICE", "CAP_SYS_RESOURCE",
+	"CAP_SYS_TIME", "CAP_SYS_TTY_CONFIG", "CAP_MKNOD", "CAP_LEASE", "CAP_AUDIT_WRITE",
+	"CAP_AUDIT_CONTROL", "CAP_SETFCAP", "CAP_MAC_OVERRIDE", "CAP_MAC_ADMIN", "CAP_SYSLOG",
+	"CAP_WAKE_ALARM", "CAP_BLOCK_SUSPEND", "CAP_AUDIT_READ", "CAP_PERFMON", "CAP_BPF",
+	"CAP_CHECKPOINT_RESTORE",
+}
+
+// rootEquivalentCapabilities are the capabilities that are enough to act as root
+var rootEquivalentCapabilities = []string{"CAP_SYS_ADMIN", "CAP_DAC_OVERRIDE", "CAP_SETUID"}
+
+// ParseCapabilities extracts the effective capabilities from the content of /proc/<pid>/status
+func ParseCapabilities(status string) ([]string, error) {
+	scanner := bufio.NewScanner(strings.NewReader(status))
+	for scanner.Scan() {
+		line := scanner.Text()
+		if !strings.HasPrefix(line, "CapEff:") {
+			continue
+		}
+		mask, err := strconv.ParseUint(strings.TrimSpace(strings.TrimPrefix(line, "CapEff:")), 16, 64)
+		if err != nil {
+			return nil, fmt.Errorf("invalid CapEff value: %w", err)
+		}
+		capabilities := []string{}
+		for bit := 0; bit < 64; bit++ {
+			if mask&(1<<bit) == 0 {
+				continue
+			}
+			if bit < len(capabilityNames) {
+				capabilities = append(capabilities, capabilityNames[bit])
+			} else {
+				capabilities = append(capabilities, fmt.Sprintf("CAP_%d", bit))
+			}
+		}
+		return capabilities, nil
+	}
+	return nil, fmt.Errorf("no CapEff line found")
+}
+
+// elevatedByCapabilities returns the first root-equivalent capability held, if any
+func elevatedByCapabilities(capabilities []string) (string, bool) {
+	for _, root := range rootEquivalentCapabilities {
+		for _, capability := range capabilities {
+			if capability == root {
+				return root, true
+			}
+		}
+	}
+	return "", false
+}
@@ -0,0 +1,31 @@
+//go:build !windows
+
+package envdetector
+
+import (
+	"fmt"
+	"os"
+	"runtime"
+)
+
+// detectPrivilege checks the effective UID and, on Linux, the effective capabilities
+func detectPrivilege() Privilege {
+	privilege := Privilege{EffectiveUID: os.Geteuid()}
+	if runtime.GOOS == "linux" {
+		if status, err := os.ReadFile("/proc/self/status"); err == nil {
+			privilege.Capabilities, _ = ParseCapabilities(string(status))
+		}
+	}
+	if privilege.EffectiveUID == 0 {
+		privilege.Elevated = true
+		privilege.Detail = "effective UID 0"
+		return privilege
+	}
+	if capability, ok := elevatedByCapabilities(privilege.Capabilities); ok {
+		privilege.Elevated = true
+		privilege.Detail = fmt.Sprintf("effective UID %d with %s", privilege.EffectiveUID, capability)
+		return privilege
+	}
+	privilege.Detail = fmt.Sprintf("effective UID %d without root-equivalent capabilities", privilege.EffectiveUID)
+	return privilege
+}
@@ -0,0 +1,42 @@
+//go:build windows
+
+package envdetector
+
+import (
+	"syscall"
+	"unsafe"
+)
+
+// tokenElevation is the TOKEN_INFORMATION_CLASS value of TokenElevation
+const tokenElevation = 20
+
+// detectPrivilege checks whether the process token is elevated
+func detectPrivilege() Privilege {
+	privilege := Privilege{EffectiveUID: -1}
+	process, err := syscall.GetCurrentProcess()
+	if err != nil {
+		privilege.Detail = "failed to open current process: " + err.Error()
+		return privilege
+	}
+	var token syscall.Token
+	if err := syscall.OpenProcessToken(process, syscall.TOKEN_QUERY, &token); err != nil {
+		privilege.Detail = "failed to open process token: " + err.Error()
+		return privilege
+	}
+	defer token.Close()
+
+	var elevated uint32
+	var returned uint32
+	err = syscall.GetTokenInformation(token, tokenElevation, (*byte)(unsafe.Pointer(&elevated)), uint32(unsafe.Sizeof(elevated)), &returned)
+	if err != nil {
+		privilege.Detail = "failed to query token elevation: " + err.Error()
+		return privilege
+	}
+	privilege.Elevated = elevated != 0
+	if privilege.Elevated {
+		privilege.Detail = "elevated token"
+	} else {
+		privilege.Detail = "non-elevated token"
+	}
+	return privilege
+}
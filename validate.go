@@ -0,0 +1,99 @@
+package main
+
+import (
+	"calderat/objects"
+	"calderat/service/knowledge"
+	logger "calderat/utils/logger"
+	"flag"
+	"fmt"
+	"os"
+	"slices"
+	"time"
+)
+
+// validate loads the abilities, adversary and fact sources of an engagement
+// without running anything and reports what would break or misbehave in a run:
+// files that do not load, duplicate ids, atomic ordering entries without an
+// ability and the problems found by objects.LintAbility. It exits 1 on any
+// problem so it can gate CI.
+func validate(args []string) int {
+	logLevel := ""
+	config, err := parseConfig("validate", args, func(flags *flag.FlagSet, config *objects.OperationConfig) {
+		bindPaths(flags, config)
+		flags.StringVar(&logLevel, "log-level", "QUIET", "Set the log level (QUIET, TRACE, DEBUG, INFO, WARN, ERROR), problems are printed either way")
+	})
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 2
+	}
+
+	log, err := logger.New(logLevel)
+	if err != nil {
+		fmt.Printf("Failed to initialize logger: %v", err)
+		return 1
+	}
+
+	problems := []string{}
+	if err := config.Validate(); err != nil {
+		problems = append(problems, fmt.Sprintf("config: %v", err))
+	}
+	if _, err := objects.NewPlanner(config.Planner); err != nil {
+		problems = append(problems, fmt.Sprintf("config: %v", err))
+	}
+	knowledgeService := knowledge.NewKnowledgeService(log)
+	abilities, err := objects.LoadAbilitiesFromDir(config.AbilitiesDir(), log, knowledgeService)
+	if err != nil {
+		for _, loadErr := range objects.LoadErrors(err) {
+			problems = append(problems, loadErr.Error())
+		}
+	}
+	defined := map[string]bool{}
+	for _, ability := range abilities {
+		defined[ability.AbilityId] = true
+	}
+
+	adversary := objects.NewAdversaryWithLogger(log)
+	if err := adversary.LoadFromYAML(config.AdversaryFile()); err != nil {
+		problems = append(problems, err.Error())
+	}
+	for index, abilityId := range adversary.AtomicOrdering {
+		if !defined[abilityId] {
+			problems = append(problems, fmt.Sprintf("%s: ability %d of the atomic ordering (%s) is not defined", config.AdversaryFile(), index+1, abilityId))
+		}
+	}
+
+	facts := 0
+	sources := []objects.Source{}
+	for _, file := range config.SourceFiles() {
+		source := objects.Source{Logger: log}
+		if err := source.LoadFromYAML(file); err != nil {
+			problems = append(problems, err.Error())
+			continue
+		}
+		sources = append(sources, source)
+		facts += len(source.Facts)
+	}
+
+	known := objects.KnownTraits(sources, abilities)
+	for _, ability := range abilities {
+		for _, problem := range objects.LintAbility(ability, known, time.Duration(config.Timeout)) {
+			problems = append(problems, fmt.Sprintf("%s: ability %s(%s): %s", ability.File, ability.Name, ability.AbilityId, problem))
+		}
+		for _, executor := range ability.Executors {
+			if executor.StopAfter != "" && !slices.Contains(adversary.AtomicOrdering, executor.StopAfter) {
+				problems = append(problems, fmt.Sprintf("%s: ability %s(%s): executor %s stops after %s, which is not in the atomic ordering",
+					ability.File, ability.Name, ability.AbilityId, executor.Name, executor.StopAfter))
+			}
+		}
+	}
+
+	for _, problem := range problems {
+		fmt.Println(problem)
+	}
+	if len(problems) > 0 {
+		fmt.Printf("%d problems found\n", len(problems))
+		return 1
+	}
+	fmt.Printf("OK: %d abilities, adversary %q with %d abilities, %d facts\n", len(abilities), adversary.Name, len(adversary.AtomicOrdering), facts)
+	return 0
+}